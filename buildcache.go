@@ -0,0 +1,208 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default size of the build cache
+const defaultBuildCacheSize = 1 << 30
+
+// buildCache is the content-addressed cache of function binaries persisted
+// between synthesis, binaries are keyed by the asset hash. The cache is
+// enabled by environment variable SCUD_BUILD_CACHE pointing to the directory
+// of the cache, its size is limited by SCUD_BUILD_CACHE_SIZE (e.g. 512M, 2G).
+// The least recently used binaries are evicted when the cache exceeds the limit.
+type buildCache struct {
+	dir  string
+	size int64
+}
+
+// openBuildCache returns the cache, it is nil if the cache is not enabled
+func openBuildCache() (*buildCache, error) {
+	dir := os.Getenv("SCUD_BUILD_CACHE")
+	if dir == "" {
+		return nil, nil
+	}
+
+	size := int64(defaultBuildCacheSize)
+	if val := os.Getenv("SCUD_BUILD_CACHE_SIZE"); val != "" {
+		n, err := parseSize(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SCUD_BUILD_CACHE_SIZE: %w", err)
+		}
+		size = n
+	}
+
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return nil, err
+	}
+
+	return &buildCache{dir: dir, size: size}, nil
+}
+
+// Get copies the binary from the cache into the output directory,
+// it returns false if the binary is not cached.
+func (c *buildCache) Get(checksum, outputDir string) (bool, error) {
+	entry := filepath.Join(c.dir, checksum)
+	if _, err := os.Stat(entry); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := os.CopyFS(outputDir, os.DirFS(entry)); err != nil {
+		return false, fmt.Errorf("unable to copy %s from build cache: %w", checksum, err)
+	}
+
+	// the modification time of entry defines the recently used binaries
+	now := time.Now()
+	if err := os.Chtimes(entry, now, now); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Put copies the binary from the output directory into the cache
+func (c *buildCache) Put(checksum, outputDir string) error {
+	entry := filepath.Join(c.dir, checksum)
+	if _, err := os.Stat(entry); err == nil {
+		return nil
+	}
+
+	tmp, err := os.MkdirTemp(c.dir, checksum+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.CopyFS(tmp, os.DirFS(outputDir)); err != nil {
+		return fmt.Errorf("unable to copy %s to build cache: %w", checksum, err)
+	}
+
+	if err := os.Rename(tmp, entry); err != nil {
+		// the entry is committed by other process concurrently
+		if _, exists := os.Stat(entry); exists == nil {
+			return nil
+		}
+		return err
+	}
+
+	return c.evict(checksum)
+}
+
+// evict removes the least recently used binaries until the cache fits
+// the limit, the recent binary is kept.
+func (c *buildCache) evict(recent string) error {
+	type entry struct {
+		name string
+		size int64
+		used time.Time
+	}
+
+	dirs, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	total := int64(0)
+	seq := []entry{}
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.Contains(dir.Name(), ".tmp-") || dir.Name() == checkCacheName {
+			continue
+		}
+
+		info, err := dir.Info()
+		if err != nil {
+			return err
+		}
+
+		size, err := dirSize(filepath.Join(c.dir, dir.Name()))
+		if err != nil {
+			return err
+		}
+
+		total += size
+		seq = append(seq, entry{name: dir.Name(), size: size, used: info.ModTime()})
+	}
+
+	sort.Slice(seq, func(i, j int) bool { return seq[i].used.Before(seq[j].used) })
+
+	for _, e := range seq {
+		if total <= c.size {
+			break
+		}
+
+		if e.name == recent {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(c.dir, e.name)); err != nil {
+			return err
+		}
+		total -= e.size
+		log.Printf("==> build cache evict %s\n", e.name[:min(8, len(e.name))])
+	}
+
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	size := int64(0)
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
+
+// parseSize parses size in bytes with optional suffix K, M or G
+func parseSize(s string) (int64, error) {
+	unit := int64(1)
+	val := strings.ToUpper(strings.TrimSpace(s))
+	switch {
+	case strings.HasSuffix(val, "K"):
+		unit, val = 1<<10, strings.TrimSuffix(val, "K")
+	case strings.HasSuffix(val, "M"):
+		unit, val = 1<<20, strings.TrimSuffix(val, "M")
+	case strings.HasSuffix(val, "G"):
+		unit, val = 1<<30, strings.TrimSuffix(val, "G")
+	}
+
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	return n * unit, nil
+}
@@ -0,0 +1,144 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Cgo configures cross-compilation of the function with cgo. The function
+// is built with C compiler targeting Linux (musl) for GOARCH of the function
+// and linked statically, the binary does not depend on libc of the runtime.
+//
+//	Cgo: &scud.Cgo{Compiler: scud.CCompilerZig}
+type Cgo struct {
+	// C compiler used for cross-compilation, default is zig cc.
+	// The compiler defined by Go environment (CC) takes precedence.
+	Compiler CCompiler
+}
+
+// CCompiler is C toolchain used by cgo
+type CCompiler string
+
+const (
+	// zig cc (https://ziglang.org), single toolchain for all targets
+	//	zig cc -target aarch64-linux-musl
+	CCompilerZig CCompiler = "zig"
+
+	// musl cross compiler (https://musl.cc)
+	//	aarch64-linux-musl-gcc
+	CCompilerMusl CCompiler = "musl"
+)
+
+// muslTarget returns target triple of the architecture
+func muslTarget(goarch string) (string, error) {
+	switch goarch {
+	case "arm64":
+		return "aarch64-linux-musl", nil
+	case "amd64":
+		return "x86_64-linux-musl", nil
+	default:
+		return "", fmt.Errorf("cgo cross-compilation does not support GOARCH=%s", goarch)
+	}
+}
+
+// env returns C toolchain of the architecture (CC and CXX)
+func (c *Cgo) env(goarch string) (map[string]string, error) {
+	target, err := muslTarget(goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	switch c.Compiler {
+	case CCompilerMusl:
+		return map[string]string{
+			"CC":  target + "-gcc",
+			"CXX": target + "-g++",
+		}, nil
+	default:
+		return map[string]string{
+			"CC":  "zig cc -target " + target,
+			"CXX": "zig c++ -target " + target,
+		}, nil
+	}
+}
+
+// tags returns build tags of static binary, resolvers of network and users
+// are pure Go, they do not depend on libc of the runtime.
+func (c *Cgo) tags() []string {
+	return []string{"netgo", "osusergo"}
+}
+
+// ldflags returns linker flags of static binary
+func (c *Cgo) ldflags() []string {
+	return []string{"-linkmode=external", "-extldflags=-static"}
+}
+
+func validateCgo(config *Toolchain) error {
+	if config.Cgo == nil {
+		return nil
+	}
+
+	switch config.Cgo.Compiler {
+	case "", CCompilerZig, CCompilerMusl:
+	default:
+		return fmt.Errorf("C compiler %s is not supported", config.Cgo.Compiler)
+	}
+
+	if config.BuildMode == BuildModeDocker || config.DockerImage != "" {
+		return fmt.Errorf("cgo cross-compilation is not supported by container-based build")
+	}
+
+	if enabled, has := config.GoEnv["CGO_ENABLED"]; has && enabled != "1" {
+		return fmt.Errorf("cgo cross-compilation requires CGO_ENABLED=1")
+	}
+
+	if goarch, has := config.GoEnv["GOARCH"]; has {
+		if _, err := muslTarget(goarch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var ccVersions sync.Map
+
+// ccVersion returns version of C compiler (e.g. "zig cc -target ..."),
+// it is used by cgo.
+func ccVersion(cc string) (string, error) {
+	seq := strings.Fields(cc)
+	if len(seq) == 0 {
+		return "", fmt.Errorf("cgo requires C compiler, CC is not defined")
+	}
+
+	bin, err := exec.LookPath(seq[0])
+	if err != nil {
+		return "", fmt.Errorf("cgo requires C compiler %s, it is not found: %w", seq[0], err)
+	}
+
+	key := bin + " " + strings.Join(seq[1:], " ")
+	if version, has := ccVersions.Load(key); has {
+		return version.(string), nil
+	}
+
+	stdout, err := exec.Command(bin, append(seq[1:], "--version")...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s --version: %w", cc, err)
+	}
+
+	version, _, _ := strings.Cut(string(stdout), "\n")
+	version = strings.TrimSpace(version)
+	ccVersions.Store(key, version)
+
+	return version, nil
+}
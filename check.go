@@ -0,0 +1,223 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckError is returned when pre-build check (go vet, go test or linter)
+// fails for the function
+type CheckError struct {
+	// Check is either vet, test or lint
+	Check string
+
+	// Package of the function
+	Package string
+
+	// Diagnostic output of the check
+	Output string
+
+	Err error
+}
+
+func (e *CheckError) Error() string {
+	return fmt.Sprintf("%s %s failed: %s\n%s", e.Check, e.Package, e.Err, e.Output)
+}
+
+func (e *CheckError) Unwrap() error { return e.Err }
+
+// check executes pre-build checks of the lambda package and its first-party
+// dependencies. Passed checks are cached by the asset hash, the configuration
+// of checks and the test files, unchanged functions are not re-checked.
+func (g *GoCompiler) check() error {
+	if !g.config.Vet && !g.config.Test && len(g.config.Linter) == 0 {
+		return nil
+	}
+
+	t := time.Now()
+
+	root, err := g.moduleDir()
+	if err != nil {
+		return err
+	}
+
+	h := NewHasher(false)
+	h.setup(g)
+	seq, _, err := h.deps()
+	if err != nil {
+		return err
+	}
+
+	pkgs := make([]string, len(seq))
+	for i, pkg := range seq {
+		pkgs[i] = pkg.ImportPath
+	}
+
+	marker, err := g.checkMarker(seq)
+	if err != nil {
+		return err
+	}
+
+	if marker != "" {
+		if _, err := os.Stat(marker); err == nil {
+			log.Printf("==> check %s (cached)\n", g.sourceCode)
+			return nil
+		}
+	}
+
+	tags := strings.Join(g.buildTags(), ",")
+
+	if g.config.Vet {
+		args := append([]string{"vet", "-tags", tags}, pkgs...)
+		if err := g.checkWithGo("vet", root, g.cmdEnv(), args); err != nil {
+			return err
+		}
+	}
+
+	if g.config.Test {
+		args := append([]string{"test", "-tags", tags}, pkgs...)
+		if err := g.checkWithGo("test", root, g.testEnv(), args); err != nil {
+			return err
+		}
+	}
+
+	if len(g.config.Linter) > 0 {
+		out := &bytes.Buffer{}
+		cmd := exec.Command(g.config.Linter[0], append(g.config.Linter[1:], pkgs...)...)
+		cmd.Dir = root
+		cmd.Env = g.cmdEnv()
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			return &CheckError{Check: "lint", Package: g.sourceCode, Output: out.String(), Err: err}
+		}
+	}
+
+	log.Printf("==> check %s (%v)\n", g.sourceCode, time.Since(t))
+
+	if marker != "" {
+		if err := os.MkdirAll(filepath.Dir(marker), 0775); err != nil {
+			return err
+		}
+		if err := os.WriteFile(marker, []byte(g.sourceCode), 0664); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *GoCompiler) checkWithGo(check, dir string, env []string, args []string) error {
+	out := &bytes.Buffer{}
+	cmd, err := g.goTool().runnerAt(dir, env, out, out)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Run(args...); err != nil {
+		return &CheckError{Check: check, Package: g.sourceCode, Output: out.String(), Err: err}
+	}
+
+	return nil
+}
+
+// testEnv is the environment of build with the platform of the host,
+// tests are executed on the host. C toolchain of cgo cross-compilation
+// (Toolchain.Cgo) targets Lambda, tests use C compiler of the host.
+func (g *GoCompiler) testEnv() []string {
+	drop := map[string]bool{"GOOS": true, "GOARCH": true}
+	if g.config.Cgo != nil {
+		for _, envvar := range []string{"CC", "CXX"} {
+			if _, has := g.config.GoEnv[envvar]; !has {
+				drop[envvar] = true
+			}
+		}
+	}
+
+	env := []string{}
+	for _, kv := range g.cmdEnv() {
+		if key, _, _ := strings.Cut(kv, "="); !drop[key] {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// checkMarker returns the file that marks passed checks, it is empty if
+// the asset hash of the function is not known.
+func (g *GoCompiler) checkMarker(seq []*goPackage) (string, error) {
+	if g.manifest == nil {
+		return "", nil
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "checksum %s\n", g.manifest.Checksum)
+	fmt.Fprintf(hash, "vet %t\n", g.config.Vet)
+	fmt.Fprintf(hash, "test %t\n", g.config.Test)
+	fmt.Fprintf(hash, "lint %s\n", strings.Join(g.config.Linter, " "))
+
+	for _, pkg := range seq {
+		files, err := filepath.Glob(filepath.Join(pkg.Dir, "*_test.go"))
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(files)
+
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return "", err
+			}
+
+			fmt.Fprintf(hash, "file %s/%s\n", pkg.ImportPath, filepath.Base(file))
+			_, err = io.Copy(hash, f)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	dir, err := checkCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%x", hash.Sum(nil))), nil
+}
+
+// checkCacheName is the directory of passed checks, it is not the entry of
+// the build cache and never evicted.
+const checkCacheName = "checks"
+
+// checkCacheDir returns directory of passed checks, it is the part of build
+// cache (SCUD_BUILD_CACHE) if the cache is enabled.
+func checkCacheDir() (string, error) {
+	if dir := os.Getenv("SCUD_BUILD_CACHE"); dir != "" {
+		return filepath.Join(dir, checkCacheName), nil
+	}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate cache of checks: %w", err)
+	}
+
+	return filepath.Join(dir, "scud", checkCacheName), nil
+}
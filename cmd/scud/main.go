@@ -0,0 +1,182 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+// The command scud is a companion tool of the library.
+//
+//	scud hash [-o manifest.json] <module> <lambda>
+//	scud hash --explain <before> <after>
+//
+// The hash command computes the asset hash of the function. With --explain
+// flag it compares two hash manifests (or directories of manifests, e.g.
+// cdk.out/scud from two builds) and explains which file, dependency or
+// toolchain setting caused the checksum to change.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fogfish/scud"
+)
+
+const usage = `Usage:
+  scud hash [-o manifest.json] <module> <lambda>
+  scud hash --explain <before> <after>
+`
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "hash" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := hash(os.Stdout, os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "scud: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func hash(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	explain := fs.Bool("explain", false, "explain difference between two manifests")
+	output := fs.String("o", "", "write manifest to the file")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if *explain {
+		return explainHash(w, fs.Arg(0), fs.Arg(1))
+	}
+
+	manifest, err := scud.NewHasher(false).Manifest(
+		scud.NewGoCompiler(fs.Arg(0), fs.Arg(1), "", nil),
+	)
+	if err != nil {
+		return err
+	}
+
+	if *output != "" {
+		if err := scud.WriteManifest(*output, manifest); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(w, manifest.Checksum)
+	return nil
+}
+
+// explainHash compares manifests, either files or directories of manifests
+func explainHash(w io.Writer, before, after string) error {
+	a, err := manifests(before)
+	if err != nil {
+		return err
+	}
+
+	b, err := manifests(after)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, has := a[name]; !has {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		x, y := a[name], b[name]
+		switch {
+		case x == nil:
+			fmt.Fprintf(w, "+ %s (%s/%s): %s\n", name, y.Module, y.Lambda, short(y.Checksum))
+		case y == nil:
+			fmt.Fprintf(w, "- %s (%s/%s): %s\n", name, x.Module, x.Lambda, short(x.Checksum))
+		case x.Checksum == y.Checksum:
+			fmt.Fprintf(w, "= %s (%s/%s): %s\n", name, y.Module, y.Lambda, short(y.Checksum))
+		default:
+			fmt.Fprintf(w, "~ %s (%s/%s): %s → %s\n", name, y.Module, y.Lambda, short(x.Checksum), short(y.Checksum))
+			for _, c := range scud.DiffManifest(x, y) {
+				fmt.Fprintf(w, "    %s\n", change(c))
+			}
+		}
+	}
+
+	return nil
+}
+
+// manifests reads the manifest file or all manifests (*.hash.json) in the directory
+func manifests(path string) (map[string]*scud.Manifest, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if fi.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.hash.json"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seq := map[string]*scud.Manifest{}
+	for _, file := range files {
+		m, err := scud.ReadManifest(file)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), ".hash.json")
+		if !fi.IsDir() {
+			name = "function"
+		}
+		seq[name] = m
+	}
+
+	return seq, nil
+}
+
+func change(c scud.ManifestChange) string {
+	switch {
+	case c.Before == nil:
+		return fmt.Sprintf("+ %-9s %s%s", c.Kind, c.Name, value(c.After))
+	case c.After == nil:
+		return fmt.Sprintf("- %-9s %s%s", c.Kind, c.Name, value(c.Before))
+	case c.Kind == scud.InputFile:
+		return fmt.Sprintf("~ %-9s %s: %s → %s", c.Kind, c.Name, short(c.Before.Digest), short(c.After.Digest))
+	default:
+		return fmt.Sprintf("~ %-9s %s: %q → %q", c.Kind, c.Name, c.Before.Value, c.After.Value)
+	}
+}
+
+func value(in *scud.ManifestInput) string {
+	if in.Kind == scud.InputFile {
+		return ""
+	}
+	return fmt.Sprintf(": %q", in.Value)
+}
+
+func short(digest string) string {
+	if len(digest) > 8 {
+		return digest[:8]
+	}
+	return digest
+}
@@ -0,0 +1,185 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Compression of the function binary
+type Compression struct {
+	// Compression method, binaries are not compressed by default
+	Method CompressionMethod
+
+	// Compression level from 1 (faster) to 9 (better), use CompressionLevelBest
+	// for the best compression. The default level of the tool is used if it is
+	// not defined.
+	Level int
+
+	// Compression algorithm, the default algorithm of the tool is used if it
+	// is not defined.
+	Strategy CompressionStrategy
+}
+
+// CompressionMethod defines the tool used to compress binaries
+type CompressionMethod string
+
+const (
+	// Binaries are not compressed
+	CompressionNone CompressionMethod = "none"
+
+	// Binaries are compressed with UPX (https://upx.github.io/),
+	// the tool must be installed on the host.
+	CompressionUPX CompressionMethod = "upx"
+)
+
+// The best compression level
+const CompressionLevelBest = 10
+
+// CompressionStrategy defines the algorithm of compression
+type CompressionStrategy string
+
+const (
+	CompressionStrategyLZMA       CompressionStrategy = "lzma"
+	CompressionStrategyNRV2B      CompressionStrategy = "nrv2b"
+	CompressionStrategyNRV2D      CompressionStrategy = "nrv2d"
+	CompressionStrategyNRV2E      CompressionStrategy = "nrv2e"
+	CompressionStrategyBrute      CompressionStrategy = "brute"
+	CompressionStrategyUltraBrute CompressionStrategy = "ultra-brute"
+)
+
+// flags returns flags of UPX, it is nil if binary is not compressed
+func (c *Compression) flags() []string {
+	if c == nil || c.Method != CompressionUPX {
+		return nil
+	}
+
+	flags := []string{"-q"}
+	switch {
+	case c.Level == CompressionLevelBest:
+		flags = append(flags, "--best")
+	case c.Level > 0:
+		flags = append(flags, fmt.Sprintf("-%d", c.Level))
+	}
+
+	if c.Strategy != "" {
+		flags = append(flags, "--"+string(c.Strategy))
+	}
+
+	return flags
+}
+
+func validateCompression(c *Compression) error {
+	if c == nil {
+		return nil
+	}
+
+	switch c.Method {
+	case "", CompressionNone, CompressionUPX:
+	default:
+		return fmt.Errorf("compression method %s is not supported", c.Method)
+	}
+
+	if c.Level < 0 || c.Level > CompressionLevelBest {
+		return fmt.Errorf("compression level %d is not supported, use 1 to 9 or CompressionLevelBest", c.Level)
+	}
+
+	switch c.Strategy {
+	case "",
+		CompressionStrategyLZMA,
+		CompressionStrategyNRV2B,
+		CompressionStrategyNRV2D,
+		CompressionStrategyNRV2E,
+		CompressionStrategyBrute,
+		CompressionStrategyUltraBrute:
+	default:
+		return fmt.Errorf("compression strategy %s is not supported", c.Strategy)
+	}
+
+	return nil
+}
+
+var upxVersions sync.Map
+
+// upxVersion returns version of UPX installed on the host
+func upxVersion() (string, error) {
+	bin, err := exec.LookPath("upx")
+	if err != nil {
+		return "", fmt.Errorf("compression requires upx (https://upx.github.io/), it is not found: %w", err)
+	}
+
+	if version, has := upxVersions.Load(bin); has {
+		return version.(string), nil
+	}
+
+	stdout, err := exec.Command(bin, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("upx --version: %w", err)
+	}
+
+	version, _, _ := strings.Cut(string(stdout), "\n")
+	version = strings.TrimSpace(version)
+	upxVersions.Store(bin, version)
+
+	return version, nil
+}
+
+// compressUPX compresses the binary, the size of binary is reported
+func compressUPX(pkg, file string, flags []string) error {
+	t := time.Now()
+
+	if _, err := upxVersion(); err != nil {
+		return err
+	}
+
+	before, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd := exec.Command("upx", append(flags, file)...)
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("upx %s: %w\n%s", pkg, err, stderr.String())
+	}
+
+	after, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("==> compress %s %s -> %s (%.1f%%) (%v)\n", pkg,
+		formatSize(before.Size()), formatSize(after.Size()),
+		100*float64(after.Size())/float64(max(before.Size(), 1)),
+		time.Since(t),
+	)
+
+	return nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
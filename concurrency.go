@@ -0,0 +1,153 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapplicationautoscaling"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// ProvisionedConcurrencyProps configures pre-initialized execution environments
+// of the function. The provisioned concurrency is allocated to the alias of
+// the function (see DeploymentProps), the alias "live" is created if the
+// function is not deployed gradually. The capacity is either fixed or scaled
+// by Application Auto Scaling, tracking the utilization or following
+// the schedule.
+type ProvisionedConcurrencyProps struct {
+	// Fixed number of execution environments, it is the minimal capacity
+	// if scaling is defined.
+	Executions int
+
+	// Maximum capacity of scaling, required by Utilization and Schedules.
+	MaxCapacity int
+
+	// Target utilization of provisioned concurrency (0, 1]
+	Utilization float64
+
+	// Scheduled capacity (e.g. business hours)
+	Schedules []ProvisionedConcurrencySchedule
+}
+
+// ProvisionedConcurrencySchedule scales the capacity at the scheduled time
+//
+//	scud.ProvisionedConcurrencySchedule{
+//	  Name:        "BusinessHours",
+//	  Schedule:    awsapplicationautoscaling.Schedule_Cron(&awsapplicationautoscaling.CronOptions{Hour: jsii.String("8"), Minute: jsii.String("0"), WeekDay: jsii.String("MON-FRI")}),
+//	  MinCapacity: 10,
+//	  MaxCapacity: 50,
+//	}
+type ProvisionedConcurrencySchedule struct {
+	Name        string
+	Schedule    awsapplicationautoscaling.Schedule
+	MinCapacity int
+	MaxCapacity int
+	TimeZone    awscdk.TimeZone
+}
+
+func (props *ProvisionedConcurrencyProps) scaling() bool {
+	return props.Utilization != 0 || len(props.Schedules) != 0
+}
+
+func validateProvisionedConcurrency(props *ProvisionedConcurrencyProps) error {
+	if props == nil {
+		return nil
+	}
+
+	if props.Executions < 0 {
+		return fmt.Errorf("provisioned concurrency %d is negative", props.Executions)
+	}
+
+	if !props.scaling() {
+		if props.Executions == 0 {
+			return fmt.Errorf("provisioned concurrency requires executions, utilization or schedules")
+		}
+		return nil
+	}
+
+	if props.MaxCapacity < max(props.Executions, 1) {
+		return fmt.Errorf("provisioned concurrency scaling requires max capacity, it is %d for %d executions", props.MaxCapacity, props.Executions)
+	}
+
+	if props.Utilization < 0 || props.Utilization > 1 {
+		return fmt.Errorf("provisioned concurrency utilization %g is not in range (0, 1]", props.Utilization)
+	}
+
+	names := map[string]struct{}{}
+	for _, schedule := range props.Schedules {
+		if schedule.Name == "" || schedule.Schedule == nil {
+			return fmt.Errorf("provisioned concurrency schedule requires name and schedule")
+		}
+		if _, has := names[schedule.Name]; has {
+			return fmt.Errorf("provisioned concurrency schedule %s is duplicated", schedule.Name)
+		}
+		names[schedule.Name] = struct{}{}
+
+		if schedule.MinCapacity < 0 || schedule.MaxCapacity < schedule.MinCapacity {
+			return fmt.Errorf("provisioned concurrency schedule %s has invalid capacity [%d, %d]", schedule.Name, schedule.MinCapacity, schedule.MaxCapacity)
+		}
+	}
+
+	return nil
+}
+
+// newProvisionedConcurrency allocates provisioned concurrency to the alias of
+// the function and scales it with Application Auto Scaling.
+func newProvisionedConcurrency(f awslambda.Function, props *ProvisionedConcurrencyProps) {
+	if props == nil {
+		return
+	}
+
+	alias := AliasOf(f)
+	if alias == nil {
+		alias = newAlias(f, "")
+	}
+
+	if props.Executions > 0 {
+		cfn := alias.Node().DefaultChild().(awslambda.CfnAlias)
+		cfn.SetProvisionedConcurrencyConfig(
+			&awslambda.CfnAlias_ProvisionedConcurrencyConfigurationProperty{
+				ProvisionedConcurrentExecutions: jsii.Number(props.Executions),
+			},
+		)
+	}
+
+	if !props.scaling() {
+		return
+	}
+
+	target := alias.AddAutoScaling(
+		&awslambda.AutoScalingOptions{
+			MinCapacity: jsii.Number(props.Executions),
+			MaxCapacity: jsii.Number(props.MaxCapacity),
+		},
+	)
+
+	if props.Utilization != 0 {
+		target.ScaleOnUtilization(
+			&awslambda.UtilizationScalingOptions{
+				UtilizationTarget: jsii.Number(props.Utilization),
+			},
+		)
+	}
+
+	for _, schedule := range props.Schedules {
+		target.ScaleOnSchedule(jsii.String(schedule.Name),
+			&awsapplicationautoscaling.ScalingSchedule{
+				Schedule:    schedule.Schedule,
+				MinCapacity: jsii.Number(schedule.MinCapacity),
+				MaxCapacity: jsii.Number(schedule.MaxCapacity),
+				TimeZone:    schedule.TimeZone,
+			},
+		)
+	}
+}
@@ -16,11 +16,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsecrassets"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
-	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
 )
@@ -58,6 +58,20 @@ type ContainerGoProps struct {
 	// Linux Alpine Packages (apk) to be installed within the container
 	// Only added to container if Dockerfile is not specified, otherwise it's caller responsibility to add them into container within Dockerfile
 	Packages []string
+
+	// Embed software bill of materials into container at /opt/sbom.{cdx|spdx}.json
+	// and refer it by the image label scud.sbom. The file is always included
+	// into build context, it's caller responsibility to add it into container
+	// if Dockerfile is specified.
+	EmbedSBOM bool
+
+	// Logging of the function, it overrides the logging configuration of
+	// the application (NewLogging).
+	Logging *LoggingProps
+
+	// Gradual deployment of the function with CodeDeploy, the function is
+	// published as version and accessed through the alias.
+	Deployment *DeploymentProps
 }
 
 func (*ContainerGoProps) HKT1(awslambda.Function) {}
@@ -67,10 +81,16 @@ func (props *ContainerGoProps) UniqueID() string {
 }
 
 func NewContainerGo(scope constructs.Construct, id *string, spec *ContainerGoProps) awslambda.Function {
+	defaults := defaultsOf(scope)
+	config := mergeToolchain(defaults.Toolchain, spec.Toolchain)
+
 	var props awslambda.DockerImageFunctionProps
 	if spec.DockerImageFunctionProps != nil {
 		props = *spec.DockerImageFunctionProps
 	}
+	if defaults.FunctionProps != nil {
+		props = mergeFunctionProps(props, *defaults.FunctionProps)
+	}
 
 	if props.Timeout == nil {
 		props.Timeout = awscdk.Duration_Minutes(jsii.Number(1))
@@ -80,23 +100,31 @@ func NewContainerGo(scope constructs.Construct, id *string, spec *ContainerGoPro
 		props.FunctionName = jsii.Sprintf("%s-%s", *awscdk.Aws_STACK_NAME(), spec.UniqueID())
 	}
 
-	if props.LogGroup == nil {
-		// See: https://docs.aws.amazon.com/lambda/latest/dg/monitoring-cloudwatchlogs-loggroups.html
-		props.LogGroup = awslogs.NewLogGroup(scope, jsii.String("LogGroup"),
-			&awslogs.LogGroupProps{
-				LogGroupName:  props.FunctionName,
-				Retention:     awslogs.RetentionDays_FIVE_DAYS,
-				RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
-			},
-		)
+	// the first container of the scope keeps the legacy id of its log group,
+	// the log group has explicit name, it would fail to be replaced.
+	logGroupID := "LogGroup"
+	if scope.Node().TryFindChild(jsii.String(logGroupID)) != nil {
+		logGroupID = "Logs" + spec.UniqueID()
+	}
+
+	logging := newFunctionLogging(scope, logGroupID, props.FunctionName, props.LogGroup, mergeLogging(defaults.Logging, spec.Logging))
+	props.LogGroup = logging.LogGroup
+	if props.LoggingFormat == "" {
+		props.LoggingFormat = logging.Format
+	}
+	if props.ApplicationLogLevelV2 == "" {
+		props.ApplicationLogLevelV2 = logging.ApplicationLogLevel
+	}
+	if props.SystemLogLevelV2 == "" {
+		props.SystemLogLevelV2 = logging.SystemLogLevel
 	}
 
 	// arm64 is default deployment
 	platContainer := "linux/arm64"
 	platCode := awsecrassets.Platform_LINUX_ARM64()
 	props.Architecture = awslambda.Architecture_ARM_64()
-	if spec.Toolchain != nil && spec.Toolchain.GoEnv != nil {
-		switch spec.Toolchain.GoEnv["GOARCH"] {
+	if config != nil && config.GoEnv != nil {
+		switch config.GoEnv["GOARCH"] {
 		case "amd64":
 			platContainer = "linux/amd64"
 			props.Architecture = awslambda.Architecture_X86_64()
@@ -112,21 +140,51 @@ func NewContainerGo(scope constructs.Construct, id *string, spec *ContainerGoPro
 		spec.SourceCodeModule,
 		spec.SourceCodeLambda,
 		spec.SourceCodeVersion,
-		spec.Toolchain,
+		config,
 	)
+	gocc.architecture = *props.Architecture.Name()
+	gocc.container = true
+
+	vulncheck(scope, *id, gocc)
 
 	path := filepath.Join(os.TempDir(), spec.SourceCodeModule, spec.SourceCodeLambda)
 	if err := os.MkdirAll(path, 0775); err != nil {
 		panic(err)
 	}
 
-	isBuild := gocc.TryBundle(jsii.String(path), nil)
-	if !*isBuild {
-		panic(fmt.Errorf("unable to build %s/%s", spec.SourceCodeModule, spec.SourceCodeLambda))
+	t := time.Now()
+	if err := gocc.check(); err != nil {
+		panic(err)
+	}
+
+	if err := gocc.Bundle(path); err != nil {
+		panic(err)
+	}
+
+	if _, err := gocc.validateBinary(path); err != nil {
+		panic(err)
+	}
+
+	info, err := readBuildInfo(path)
+	if err != nil {
+		panic(err)
+	}
+	gocc.buildInfo = info
+
+	report, err := gocc.inspect(path, time.Since(t))
+	if err != nil {
+		panic(err)
 	}
 
+	root, err := gocc.moduleDir()
+	if err != nil {
+		panic(err)
+	}
+
+	sbom := dockerSBOM(path, spec, gocc)
+
 	if spec.Dockerfile != "" {
-		source := filepath.Join(rootSourceCode(spec.SourceCodeModule), spec.Dockerfile)
+		source := filepath.Join(root, spec.Dockerfile)
 		target := filepath.Join(path, "Dockerfile")
 		log.Printf("==> copy %s\n", spec.Dockerfile)
 		if err := copy(source, target); err != nil {
@@ -137,10 +195,11 @@ func NewContainerGo(scope constructs.Construct, id *string, spec *ContainerGoPro
 FROM %s
 %s
 %s
+%s
 ADD bootstrap /bin/bootstrap
 
 CMD ["/bin/bootstrap"]
-	`, dockerBaseImage(spec), dockerPackages(spec), dockerAssets(path, spec))
+	`, dockerBaseImage(spec), dockerPackages(spec), dockerAssets(root, path, spec), sbom)
 
 		err := os.WriteFile(filepath.Join(path, "Dockerfile"), []byte(docker), 0664)
 		if err != nil {
@@ -158,7 +217,19 @@ CMD ["/bin/bootstrap"]
 		},
 	)
 
-	return awslambda.NewDockerImageFunction(scope, id, &props)
+	f := awslambda.NewDockerImageFunction(scope, id, &props)
+
+	if file := artifactPath(f, ".build.json"); file != "" {
+		if err := WriteBuildReport(file, report); err != nil {
+			panic(err)
+		}
+	}
+
+	writeSBOM(f, gocc)
+	newDeployment(f, spec.Deployment)
+	defaults.tag(f)
+
+	return f
 }
 
 func dockerBaseImage(spec *ContainerGoProps) string {
@@ -179,9 +250,7 @@ func dockerPackages(spec *ContainerGoProps) string {
 	)
 }
 
-func dockerAssets(path string, spec *ContainerGoProps) string {
-	root := rootSourceCode(spec.SourceCodeModule)
-
+func dockerAssets(root, path string, spec *ContainerGoProps) string {
 	assets := []string{}
 	for _, asset := range spec.StaticAssets {
 		source := filepath.Join(root, asset)
@@ -201,6 +270,25 @@ func dockerAssets(path string, spec *ContainerGoProps) string {
 	return strings.Join(assets, "\n")
 }
 
+// dockerSBOM writes SBOM into build context if it is embedded into container
+func dockerSBOM(path string, spec *ContainerGoProps, gocc *GoCompiler) string {
+	if !spec.EmbedSBOM {
+		return ""
+	}
+
+	sbom, err := gocc.SBOM()
+	if err != nil {
+		panic(err)
+	}
+
+	file := "sbom" + gocc.config.SBOM.suffix()
+	if err := os.WriteFile(filepath.Join(path, file), sbom, 0664); err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("ADD %s /opt/%s\nLABEL scud.sbom=/opt/%s", file, file, file)
+}
+
 func copy(source, target string) (err error) {
 	r, err := os.Open(source)
 	if err != nil {
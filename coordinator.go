@@ -0,0 +1,284 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/constructs-go/constructs/v10"
+)
+
+// BuildCoordinator compiles functions of the application concurrently.
+// Functions are registered with the coordinator before they are created.
+// The coordinator resolves dependencies of all registered functions with
+// single `go list -deps` call, builds each unique asset (functions with equal
+// asset hash are built once) using the limited number of workers. The function
+// created by NewFunctionGo hands over the binary to AWS CDK as usual.
+//
+//	builds := scud.NewBuildCoordinator(8)
+//	defer builds.Close()
+//
+//	a := &scud.FunctionGoProps{...}
+//	b := &scud.FunctionGoProps{...}
+//	builds.Add(stack, a, b)
+//	if err := builds.Build(); err != nil {
+//	  panic(err)
+//	}
+//
+//	scud.NewFunctionGo(stack, jsii.String("A"), a)
+//	scud.NewFunctionGo(stack, jsii.String("B"), b)
+type BuildCoordinator struct {
+	workers int
+	specs   []*FunctionGoProps
+	dir     string
+	golist  *goListCache
+}
+
+// goBuild is the result of the function build by coordinator
+type goBuild struct {
+	dir      string
+	duration time.Duration
+	err      error
+}
+
+// copyTo copies the binary into the output directory of the asset
+func (b *goBuild) copyTo(outputDir string) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	return os.CopyFS(outputDir, os.DirFS(b.dir))
+}
+
+// NewBuildCoordinator creates the coordinator with the limit of concurrent
+// workers. The limit is defined by environment variable SCUD_BUILD_WORKERS
+// or the number of CPUs if workers is not positive.
+func NewBuildCoordinator(workers int) *BuildCoordinator {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+		if n, err := strconv.Atoi(os.Getenv("SCUD_BUILD_WORKERS")); err == nil && n > 0 {
+			workers = n
+		}
+	}
+
+	return &BuildCoordinator{workers: workers, golist: &goListCache{}}
+}
+
+// Add registers functions with the coordinator, the toolchain of functions
+// is resolved with defaults (NewDefaults) of the scope where functions are
+// created. Defaults are declared before functions are registered.
+func (c *BuildCoordinator) Add(scope constructs.Construct, specs ...*FunctionGoProps) {
+	defaults := defaultsOf(scope)
+
+	outdir := ""
+	if stage := awscdk.Stage_Of(scope); stage != nil && stage.Outdir() != nil {
+		outdir = *stage.Outdir()
+	}
+
+	for _, spec := range specs {
+		spec.compilerConfig = mergeToolchain(defaults.Toolchain, spec.toolchain())
+		spec.compilerOutdir = outdir
+	}
+
+	c.specs = append(c.specs, specs...)
+}
+
+// Build compiles all registered functions, it returns errors of
+// all failed builds (CompileError). Functions staged by previous synthesis
+// are not compiled, AWS CDK does not bundle them again.
+func (c *BuildCoordinator) Build() error {
+	t := time.Now()
+
+	seq := []*GoCompiler{}
+	outdirs := []string{}
+	for _, spec := range c.specs {
+		if spec.compiler == nil {
+			spec.compiler = NewGoCompiler(
+				spec.SourceCodeModule,
+				spec.SourceCodeLambda,
+				spec.SourceCodeVersion,
+				spec.compilerConfig,
+			)
+			spec.compiler.golist = c.golist
+			seq = append(seq, spec.compiler)
+			outdirs = append(outdirs, spec.compilerOutdir)
+		}
+	}
+
+	if len(seq) == 0 {
+		return nil
+	}
+
+	if err := c.resolve(seq); err != nil {
+		return err
+	}
+
+	if err := c.hash(seq); err != nil {
+		return err
+	}
+
+	// AWS CDK calls TryBundle if the asset is not staged, the function is
+	// compiled on demand then.
+	pending := []*GoCompiler{}
+	for i, gocc := range seq {
+		if _, err := os.Stat(assetArtifactPath(outdirs[i], gocc.manifest.Checksum, ".build.json")); err == nil {
+			continue
+		}
+		pending = append(pending, gocc)
+	}
+
+	builds, err := c.build(pending)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("==> go build %d functions (%d unique, %d staged, %d workers) (%v)\n", len(seq), len(builds), len(seq)-len(pending), c.workers, time.Since(t))
+
+	errs := []error{}
+	for _, b := range builds {
+		if b.err != nil {
+			errs = append(errs, b.err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// resolve dependencies of functions, one `go list` per build configuration
+func (c *BuildCoordinator) resolve(seq []*GoCompiler) error {
+	type config struct {
+		tool  goTool
+		dir   string
+		env   []string
+		flags []string
+		pkgs  []string
+	}
+
+	configs := map[string]*config{}
+	keys := []string{}
+	for _, gocc := range seq {
+		dir, err := gocc.moduleDir()
+		if err != nil {
+			return err
+		}
+
+		tool, env, flags := gocc.goTool(), gocc.BuildEnv(), gocc.BuildFlags()
+		key := tool.key() + "|" + dir + "|" + strings.Join(env, " ") + "|" + strings.Join(flags, " ")
+		if _, has := configs[key]; !has {
+			configs[key] = &config{tool: tool, dir: dir, env: env, flags: flags}
+			keys = append(keys, key)
+		}
+		configs[key].pkgs = append(configs[key].pkgs, path.Join(gocc.sourceCodePackage, gocc.sourceCodeLambda))
+	}
+
+	for _, key := range keys {
+		cfg := configs[key]
+		if err := cfg.tool.listDeps(cfg.dir, cfg.env, cfg.flags, cfg.pkgs...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hash computes asset hash of functions
+func (c *BuildCoordinator) hash(seq []*GoCompiler) error {
+	verbose := os.Getenv("SCUD_HASH_VERBOSE") == "1"
+	errs := make([]error, len(seq))
+
+	c.parallel(len(seq), func(i int) {
+		seq[i].manifest, errs[i] = NewHasher(verbose).Manifest(seq[i])
+	})
+
+	return errors.Join(errs...)
+}
+
+// build compiles unique assets, functions with equal hash share the build
+func (c *BuildCoordinator) build(seq []*GoCompiler) ([]*goBuild, error) {
+	if c.dir == "" {
+		dir, err := os.MkdirTemp("", "scud-build-")
+		if err != nil {
+			return nil, err
+		}
+		c.dir = dir
+	}
+
+	unique := map[string]*goBuild{}
+	builds := []*goBuild{}
+	compilers := []*GoCompiler{}
+	for _, gocc := range seq {
+		checksum := gocc.manifest.Checksum
+		if b, has := unique[checksum]; has {
+			gocc.build = b
+			continue
+		}
+
+		b := &goBuild{dir: filepath.Join(c.dir, checksum)}
+		if err := os.MkdirAll(b.dir, 0775); err != nil {
+			return nil, err
+		}
+
+		unique[checksum] = b
+		builds = append(builds, b)
+		compilers = append(compilers, gocc)
+		gocc.build = b
+	}
+
+	c.parallel(len(builds), func(i int) {
+		t := time.Now()
+		builds[i].err = compilers[i].bundleWithCache(builds[i].dir)
+		builds[i].duration = time.Since(t)
+	})
+
+	return builds, nil
+}
+
+// parallel executes n jobs using limited number of workers
+func (c *BuildCoordinator) parallel(n int, job func(int)) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, c.workers)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			job(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// Close removes binaries built by the coordinator,
+// it is called after functions are created.
+func (c *BuildCoordinator) Close() error {
+	if c.dir == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("unable to clean build directory: %w", err)
+	}
+
+	c.dir = ""
+	return nil
+}
@@ -0,0 +1,220 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// DefaultsProps declares defaults of scud functions
+type DefaultsProps struct {
+	// Default toolchain, the toolchain of the function overrides it field by
+	// field. Maps (GoEnv, LDVars) are merged by key, build tags are merged,
+	// other lists are replaced, checks (Vet, Test) are enabled if either
+	// defines them.
+	Toolchain *Toolchain
+
+	// Default properties of functions (e.g. Timeout, MemorySize, Environment),
+	// the property is used if the function does not define it. Environment
+	// is merged by key. FunctionName, Code and Handler are not used.
+	// Properties are also applied to containers.
+	FunctionProps *awslambda.FunctionProps
+
+	// Tags applied to every function
+	Tags map[string]string
+
+	// Default logging of functions, see FunctionGoProps.Logging. The log group
+	// shared by the application is defined by NewLogging.
+	Logging *LoggingProps
+}
+
+// Defaults of scud functions created beneath the scope of the construct
+// (App, Stage, Stack or any construct). Defaults of nested scopes are merged,
+// the nearest scope takes precedence.
+type Defaults struct {
+	constructs.Construct
+	props *DefaultsProps
+}
+
+// NewDefaults declares defaults of scud functions created beneath the scope,
+// the scope has at most one declaration of defaults.
+func NewDefaults(scope constructs.Construct, id *string, props *DefaultsProps) *Defaults {
+	if props == nil {
+		props = &DefaultsProps{}
+	}
+
+	if defaultsAt(scope) != nil {
+		panic(fmt.Errorf("defaults are already declared at scope %q", *scope.Node().Path()))
+	}
+
+	// the construct is created with override so that the construct tree
+	// refers to *Defaults, it is resolved by defaultsOf.
+	d := &Defaults{props: props}
+	constructs.NewConstruct_Override(d, scope, id)
+
+	return d
+}
+
+// Props returns declared defaults
+func (d *Defaults) Props() *DefaultsProps { return d.props }
+
+// defaultsAt returns defaults declared at the scope
+func defaultsAt(scope constructs.IConstruct) *Defaults {
+	for _, child := range *scope.Node().Children() {
+		if d, ok := child.(*Defaults); ok {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// defaultsOf returns defaults of the scope, merged from the root
+func defaultsOf(scope constructs.Construct) *DefaultsProps {
+	seq := []*DefaultsProps{}
+
+	var node constructs.IConstruct = scope
+	for ; node != nil; node = node.Node().Scope() {
+		if d := defaultsAt(node); d != nil {
+			seq = append(seq, d.props)
+		}
+	}
+
+	props := &DefaultsProps{}
+	for _, d := range slices.Backward(seq) {
+		props = props.merge(d)
+	}
+
+	return props
+}
+
+// merge returns defaults overridden by other one
+func (props *DefaultsProps) merge(override *DefaultsProps) *DefaultsProps {
+	merged := &DefaultsProps{
+		Toolchain:     mergeToolchain(props.Toolchain, override.Toolchain),
+		FunctionProps: props.FunctionProps,
+		Tags:          map[string]string{},
+		Logging:       mergeLogging(props.Logging, override.Logging),
+	}
+
+	if override.FunctionProps != nil {
+		fprops := awslambda.FunctionProps{}
+		if props.FunctionProps != nil {
+			fprops = *props.FunctionProps
+		}
+		fprops = mergeFunctionProps(*override.FunctionProps, fprops)
+		merged.FunctionProps = &fprops
+	}
+
+	maps.Copy(merged.Tags, props.Tags)
+	maps.Copy(merged.Tags, override.Tags)
+
+	return merged
+}
+
+// tag applies tags to the function, tags are sorted to keep template stable
+func (props *DefaultsProps) tag(f constructs.Construct) {
+	keys := slices.Collect(maps.Keys(props.Tags))
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		awscdk.Tags_Of(f).Add(jsii.String(key), jsii.String(props.Tags[key]), nil)
+	}
+}
+
+func mergeLogging(base, override *LoggingProps) *LoggingProps {
+	if base == nil {
+		return override
+	}
+
+	merged := base.merge(override)
+	return &merged
+}
+
+// mergeToolchain returns toolchain overridden by other one
+func mergeToolchain(base, override *Toolchain) *Toolchain {
+	switch {
+	case base == nil:
+		return override
+	case override == nil:
+		return base
+	}
+
+	config := *override
+
+	config.GoEnv = map[string]string{}
+	maps.Copy(config.GoEnv, base.GoEnv)
+	maps.Copy(config.GoEnv, override.GoEnv)
+
+	config.LDVars = map[string]string{}
+	maps.Copy(config.LDVars, base.LDVars)
+	maps.Copy(config.LDVars, override.LDVars)
+
+	config.Tags = append(append([]string{}, base.Tags...), override.Tags...)
+	config.Vet = base.Vet || override.Vet
+	config.Test = base.Test || override.Test
+
+	// other fields are used if they are not defined by the override
+	dst := reflect.ValueOf(&config).Elem()
+	src := reflect.ValueOf(base).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		if dst.Field(i).IsZero() {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+
+	return &config
+}
+
+// mergeFunctionProps sets properties of the function that are not defined
+// from defaults. Environment is merged by key.
+func mergeFunctionProps[T any](props T, base awslambda.FunctionProps) T {
+	dst := reflect.ValueOf(&props).Elem()
+	src := reflect.ValueOf(base)
+
+	for i := 0; i < src.NumField(); i++ {
+		name := src.Type().Field(i).Name
+		switch name {
+		case "FunctionName", "Code", "Handler":
+			continue
+		}
+
+		field := dst.FieldByName(name)
+		if !field.IsValid() || !field.CanSet() || src.Field(i).IsZero() {
+			continue
+		}
+
+		if field.Type() != src.Field(i).Type() {
+			continue
+		}
+
+		if name == "Environment" && !field.IsZero() {
+			env := map[string]*string{}
+			maps.Copy(env, *base.Environment)
+			maps.Copy(env, *field.Interface().(*map[string]*string))
+			field.Set(reflect.ValueOf(&env))
+			continue
+		}
+
+		if field.IsZero() {
+			field.Set(src.Field(i))
+		}
+	}
+
+	return props
+}
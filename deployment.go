@@ -0,0 +1,136 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscloudwatch"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodedeploy"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// DeploymentProps configures gradual deployment of the function. The version
+// of the function is published on each change of the function (e.g. asset
+// hash), the alias refers to the latest version. The traffic is shifted to
+// new version by CodeDeploy, the deployment is rolled back if it fails or
+// any of alarms is triggered.
+type DeploymentProps struct {
+	// Name of the alias, default is "live"
+	Alias string
+
+	// Traffic shifting (canary, linear or all at once), default is all at once
+	//	Config: awscodedeploy.LambdaDeploymentConfig_CANARY_10PERCENT_5MINUTES()
+	Config awscodedeploy.ILambdaDeploymentConfig
+
+	// Alarms that roll back the deployment
+	Alarms []awscloudwatch.IAlarm
+
+	// Roll back the deployment if new version reports any error
+	RollbackOnErrors bool
+
+	// Functions executed by CodeDeploy before and after traffic shifting
+	PreHook  awslambda.IFunction
+	PostHook awslambda.IFunction
+}
+
+const defaultAlias = "live"
+
+// AliasOf returns the alias of scud function, it is nil if the function is
+// not deployed with alias.
+func AliasOf(f awslambda.IFunction) awslambda.Alias {
+	if f == nil {
+		return nil
+	}
+
+	if alias, ok := f.Node().TryFindChild(jsii.String("Alias")).(awslambda.Alias); ok {
+		return alias
+	}
+
+	return nil
+}
+
+// invokeTarget returns the alias of the function if it is defined, so that
+// clients (e.g. Gateway) invoke the version deployed by CodeDeploy instead of
+// $LATEST.
+func invokeTarget(f awslambda.IFunction) awslambda.IFunction {
+	if alias := AliasOf(f); alias != nil {
+		return alias
+	}
+
+	return f
+}
+
+// newAlias creates the alias of the latest version of the function
+func newAlias(f awslambda.Function, name string) awslambda.Alias {
+	if name == "" {
+		name = defaultAlias
+	}
+
+	alias := awslambda.NewAlias(f, jsii.String("Alias"),
+		&awslambda.AliasProps{
+			AliasName: jsii.String(name),
+			Version:   f.CurrentVersion(),
+		},
+	)
+
+	return alias
+}
+
+// newDeployment creates the alias and CodeDeploy deployment group of
+// the function
+func newDeployment(f awslambda.Function, props *DeploymentProps) {
+	if props == nil {
+		return
+	}
+
+	alias := newAlias(f, props.Alias)
+
+	alarms := append([]awscloudwatch.IAlarm{}, props.Alarms...)
+	if props.RollbackOnErrors {
+		alarms = append(alarms,
+			alias.MetricErrors(&awscloudwatch.MetricOptions{
+				Period:    awscdk.Duration_Minutes(jsii.Number(1)),
+				Statistic: jsii.String("Sum"),
+			}).CreateAlarm(f, jsii.String("ErrorsAlarm"),
+				&awscloudwatch.CreateAlarmOptions{
+					Threshold:          jsii.Number(1),
+					EvaluationPeriods:  jsii.Number(1),
+					ComparisonOperator: awscloudwatch.ComparisonOperator_GREATER_THAN_OR_EQUAL_TO_THRESHOLD,
+					TreatMissingData:   awscloudwatch.TreatMissingData_NOT_BREACHING,
+				},
+			),
+		)
+	}
+
+	config := props.Config
+	if config == nil {
+		config = awscodedeploy.LambdaDeploymentConfig_ALL_AT_ONCE()
+	}
+
+	var alarmsProp *[]awscloudwatch.IAlarm
+	if len(alarms) > 0 {
+		alarmsProp = &alarms
+	}
+
+	awscodedeploy.NewLambdaDeploymentGroup(f, jsii.String("Deployment"),
+		&awscodedeploy.LambdaDeploymentGroupProps{
+			Alias:            alias,
+			DeploymentConfig: config,
+			Alarms:           alarmsProp,
+			PreHook:          props.PreHook,
+			PostHook:         props.PostHook,
+			AutoRollback: &awscodedeploy.AutoRollbackConfig{
+				FailedDeployment:  jsii.Bool(true),
+				StoppedDeployment: jsii.Bool(true),
+				DeploymentInAlarm: jsii.Bool(len(alarms) > 0),
+			},
+		},
+	)
+}
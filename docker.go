@@ -0,0 +1,207 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Go toolchain inside container uses following directories, they are mounted
+// from the host so that module and build caches survive between builds.
+const (
+	dockerGoModCache  = "/go/pkg/mod"
+	dockerGoCache     = "/go/cache"
+	dockerAssetOutput = "/asset-output"
+)
+
+// goRunner executes `go` command
+type goRunner interface {
+	Run(args ...string) error
+}
+
+// localGo is `go` command executed on the host
+type localGo struct {
+	dir    string
+	env    []string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (l *localGo) Run(args ...string) error {
+	if _, err := exec.LookPath("go"); err != nil {
+		return fmt.Errorf("go toolchain is not found, use BuildModeDocker (SCUD_BUILD_MODE=docker) to build inside container: %w", err)
+	}
+
+	cmd := exec.Command("go", args...)
+	cmd.Dir = l.dir
+	cmd.Env = l.env
+	cmd.Stdout = l.stdout
+	cmd.Stderr = l.stderr
+
+	return cmd.Run()
+}
+
+// dockerGo is `go` command executed inside golang container. The source code
+// root is mounted at same absolute path, therefore paths reported by
+// the toolchain (e.g. `go list`) are valid on the host.
+type dockerGo struct {
+	image  string
+	root   string
+	dir    string
+	env    []string
+	mounts map[string]string
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func (d *dockerGo) Run(args ...string) error {
+	modcache, gocache, err := dockerGoCacheDirs()
+	if err != nil {
+		return err
+	}
+
+	dir := d.dir
+	if dir == "" {
+		dir = d.root
+	}
+
+	cmd := []string{"run", "--rm"}
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 {
+		cmd = append(cmd, "-u", fmt.Sprintf("%d:%d", uid, gid))
+	}
+
+	cmd = append(cmd,
+		"-v", d.root+":"+d.root,
+		"-v", modcache+":"+dockerGoModCache,
+		"-v", gocache+":"+dockerGoCache,
+	)
+	for host, container := range d.mounts {
+		cmd = append(cmd, "-v", host+":"+container)
+	}
+
+	cmd = append(cmd,
+		"-w", dir,
+		"-e", "HOME=/tmp",
+		"-e", "GOMODCACHE="+dockerGoModCache,
+		"-e", "GOCACHE="+dockerGoCache,
+	)
+	for _, kv := range d.env {
+		key, _, _ := strings.Cut(kv, "=")
+		if !isLocalGoEnv(key) {
+			cmd = append(cmd, "-e", kv)
+		}
+	}
+
+	cmd = append(cmd, d.image, "go")
+	cmd = append(cmd, args...)
+
+	docker := exec.Command("docker", cmd...)
+	docker.Stdout = d.stdout
+	docker.Stderr = d.stderr
+
+	return docker.Run()
+}
+
+// dockerGoCacheDirs returns host directories of module and build caches
+// mounted into container. GOMODCACHE of the host is re-used if it is defined.
+func dockerGoCacheDirs() (string, string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", "", err
+	}
+
+	modcache := os.Getenv("GOMODCACHE")
+	if modcache == "" {
+		modcache = filepath.Join(cache, "scud", "gomodcache")
+	}
+
+	gocache := filepath.Join(cache, "scud", "gocache")
+
+	for _, dir := range []string{modcache, gocache} {
+		if err := os.MkdirAll(dir, 0775); err != nil {
+			return "", "", err
+		}
+	}
+
+	return modcache, gocache, nil
+}
+
+// dockerGoImage returns golang image pinned to the version of Go required by
+// the source code (toolchain or go directive of go.work or go.mod).
+func dockerGoImage(root string) string {
+	f, err := os.Open(filepath.Join(root, "go.work"))
+	if err != nil {
+		f, err = os.Open(filepath.Join(root, "go.mod"))
+	}
+	if err != nil {
+		return "golang"
+	}
+	defer f.Close()
+
+	version := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.Fields(s.Text())
+		if len(line) != 2 {
+			continue
+		}
+
+		switch line[0] {
+		case "go":
+			if version == "" {
+				version = line[1]
+			}
+		case "toolchain":
+			version = strings.TrimPrefix(line[1], "go")
+		}
+	}
+
+	if version == "" {
+		return "golang"
+	}
+
+	return "golang:" + version
+}
+
+// dockerSourceRoot returns the root of source code containing the directory,
+// it is either the directory of Go workspace (go.work) or the module (go.mod).
+// It is used to mount the source code into container.
+func dockerSourceRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	root := ""
+	for path := dir; ; path = filepath.Dir(path) {
+		if _, err := os.Stat(filepath.Join(path, "go.work")); err == nil {
+			return path, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && root == "" {
+			root = path
+		}
+
+		if filepath.Dir(path) == path {
+			break
+		}
+	}
+
+	if root == "" {
+		return "", fmt.Errorf("go.mod is not found at %s", dir)
+	}
+
+	return root, nil
+}
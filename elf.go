@@ -0,0 +1,197 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"debug/elf"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BinaryError is returned when the binary of the function is not compatible
+// with Lambda runtime (e.g. architecture or dynamic linking)
+type BinaryError struct {
+	Package string
+	Reason  string
+}
+
+func (e *BinaryError) Error() string {
+	return fmt.Sprintf("binary of %s is not compatible with Lambda: %s", e.Package, e.Reason)
+}
+
+// lambdaMachine maps Lambda architecture to ELF machine
+var lambdaMachine = map[string]elf.Machine{
+	"arm64":  elf.EM_AARCH64,
+	"x86_64": elf.EM_X86_64,
+}
+
+// goarchLambda maps GOARCH to Lambda architecture
+var goarchLambda = map[string]string{
+	"arm64": "arm64",
+	"amd64": "x86_64",
+}
+
+// elfBinary is the subset of ELF metadata required to validate the binary
+type elfBinary struct {
+	OSABI   elf.OSABI
+	Machine elf.Machine
+	Static  bool
+
+	// dynamic loader and the latest version of glibc required by
+	// dynamically linked binary
+	Interp string
+	GLIBC  string
+}
+
+// readELF reads ELF metadata of the binary, the binary is statically linked
+// if it does not require the interpreter (dynamic loader) and shared libraries.
+func readELF(file string) (*elfBinary, error) {
+	f, err := elf.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	bin := &elfBinary{OSABI: f.OSABI, Machine: f.Machine, Static: true}
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			bin.Static = false
+
+			interp, err := io.ReadAll(prog.Open())
+			if err != nil {
+				return nil, err
+			}
+			bin.Interp = strings.TrimRight(string(interp), "\x00")
+		}
+	}
+
+	libs, err := f.ImportedLibraries()
+	if err == nil && len(libs) > 0 {
+		bin.Static = false
+	}
+
+	syms, err := f.ImportedSymbols()
+	if err == nil {
+		for _, sym := range syms {
+			version, has := strings.CutPrefix(sym.Version, "GLIBC_")
+			if has && (bin.GLIBC == "" || semverCompare(version, bin.GLIBC) > 0) {
+				bin.GLIBC = version
+			}
+		}
+	}
+
+	return bin, nil
+}
+
+// lambdaArchitecture returns Lambda architecture of the function, it is
+// either configured by the construct or derived from GOARCH.
+func (g *GoCompiler) lambdaArchitecture() string {
+	if g.architecture != "" {
+		return g.architecture
+	}
+
+	if arch, has := goarchLambda[g.goenv("GOARCH")]; has {
+		return arch
+	}
+
+	return g.goenv("GOARCH")
+}
+
+// validateBinary checks that the binary is Linux executable (ELF) built for
+// Lambda architecture of the function. The binary must be statically linked
+// unless it is built with cgo for runtime's libc.
+func (g *GoCompiler) validateBinary(outputDir string) (*elfBinary, error) {
+	bin, err := readELF(filepath.Join(outputDir, goBinary))
+	if err != nil {
+		return nil, &BinaryError{Package: g.sourceCode,
+			Reason: fmt.Sprintf("binary is not Linux executable (GOOS=%s): %s", g.goenv("GOOS"), err)}
+	}
+
+	return bin, g.validateELF(bin)
+}
+
+// validateELF checks ELF metadata of the binary, the metadata of staged
+// asset is validated without the binary.
+func (g *GoCompiler) validateELF(bin *elfBinary) error {
+	if bin.OSABI != elf.ELFOSABI_NONE && bin.OSABI != elf.ELFOSABI_LINUX {
+		return &BinaryError{Package: g.sourceCode,
+			Reason: fmt.Sprintf("binary is built for %s (GOOS=%s), expected Linux", bin.OSABI, g.goenv("GOOS"))}
+	}
+
+	arch := g.lambdaArchitecture()
+	if machine, has := lambdaMachine[arch]; !has || bin.Machine != machine {
+		return &BinaryError{Package: g.sourceCode,
+			Reason: fmt.Sprintf("binary is built for %s (GOARCH=%s), Lambda architecture is %s", bin.Machine, g.goenv("GOARCH"), arch)}
+	}
+
+	if !bin.Static && (g.config.Cgo != nil || g.goenv("CGO_ENABLED") != "1") {
+		return &BinaryError{Package: g.sourceCode, Reason: "binary is not statically linked"}
+	}
+
+	if !bin.Static && !g.container {
+		return g.validateRuntime(bin)
+	}
+
+	return nil
+}
+
+// writeELF persists ELF metadata of the binary as JSON file
+func writeELF(file string, bin *elfBinary) error {
+	b, err := json.Marshal(bin)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b, 0664)
+}
+
+// readELFOf reads ELF metadata of the binary from JSON file
+func readELFOf(file string) (*elfBinary, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var bin elfBinary
+	if err := json.Unmarshal(b, &bin); err != nil {
+		return nil, fmt.Errorf("invalid ELF metadata %s: %w", file, err)
+	}
+
+	return &bin, nil
+}
+
+// validateRuntime checks that dynamically linked binary is compatible with
+// glibc provided by Lambda runtime
+func (g *GoCompiler) validateRuntime(bin *elfBinary) error {
+	runtime := g.runtime
+	if runtime == "" {
+		runtime = defaultRuntime
+	}
+
+	glibc, has := lambdaRuntimes[runtime]
+	if !has {
+		return nil
+	}
+
+	if !strings.Contains(bin.Interp, "ld-linux") {
+		return &BinaryError{Package: g.sourceCode,
+			Reason: fmt.Sprintf("binary requires dynamic loader %s, it is not available in runtime %s", bin.Interp, runtime)}
+	}
+
+	if bin.GLIBC != "" && semverCompare(bin.GLIBC, glibc) > 0 {
+		return &BinaryError{Package: g.sourceCode,
+			Reason: fmt.Sprintf("binary requires GLIBC_%s, runtime %s provides GLIBC_%s, link the binary statically (Toolchain.Cgo) or use newer runtime", bin.GLIBC, runtime, glibc)}
+	}
+
+	return nil
+}
@@ -1,12 +1,19 @@
 package scud
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -28,18 +35,160 @@ type Toolchain struct {
 	// Go variables injected at link time using -X key=value.
 	// Example: -ldflags "-X main.version=1.0.0"
 	LDVars map[string]string
+
+	// Build tags merged with default tag lambda.norpc.
+	//	Tags: []string{"dynamodb"}
+	Tags []string
+
+	// Additional flags of `go build` merged with defaults, the flag with value
+	// is given in the form -flag=value. The build tags (-tags), the linker
+	// flags (-ldflags) and the output (-o) are configured by scud.
+	//	Flags: []string{"-trimpath", "-buildvcs=false", "-gcflags=all=-l"}
+	Flags []string
+
+	// Profile for profile-guided optimization (-pgo), the path is relative
+	// to the module. The profile default.pgo next to the main package of
+	// the function is used if the path is not defined, "off" disables PGO.
+	//	PGO: "profiles/cpu.pprof"
+	PGO string
+
+	// Compression of the binary, binaries are not compressed by default.
+	// Compression with UPX is enabled for all functions with environment
+	// variable SCUD_COMPRESS_UPX=1 unless it is defined here.
+	//	Compression: &scud.Compression{Method: scud.CompressionUPX, Level: 9}
+	Compression *Compression
+
+	// Size budget of the binary in bytes, the synthesis fails if the binary
+	// (after compression) exceeds the budget. The budget is not defined by
+	// default.
+	//	MaxBinarySize: 20 << 20
+	MaxBinarySize int64
+
+	// Run `go vet` for the function and its first-party packages before build
+	Vet bool
+
+	// Run `go test` for the function and its first-party packages before
+	// build, tests are executed on the platform of the host.
+	Test bool
+
+	// External linter executed within the module before build, the packages
+	// of the function are appended to the command.
+	//	Linter: []string{"staticcheck", "-checks", "all"}
+	Linter []string
+
+	// Scan dependencies of the function for known vulnerabilities using
+	// offline vulnerability database, the scan is disabled by default.
+	//	Vulncheck: &scud.Vulncheck{DB: "vulndb.zip", Severity: scud.SeverityHigh}
+	Vulncheck *Vulncheck
+
+	// Cross-compilation of the function with cgo, the function is built with
+	// C compiler for the target architecture (e.g. zig cc) and linked
+	// statically. Cgo is disabled by default.
+	//	Cgo: &scud.Cgo{Compiler: scud.CCompilerZig}
+	Cgo *Cgo
+
+	// Format of software bill of materials (SBOM) emitted for the function,
+	// default is CycloneDX.
+	SBOM SBOMFormat
+
+	// Canonical names (prefixes) of modules treated as first-party code,
+	// their source code is included into the asset hash. The module of
+	// the function, modules of Go workspace (go.work) and modules replaced
+	// with local directory are always first-party.
+	//	LocalModules: []string{"github.com/fogfish"}
+	LocalModules []string
+
+	// Build mode, the function is built with local Go toolchain by default.
+	// The failure of local build aborts synthesis, the container-based build
+	// is used only if it is explicitly configured here or with environment
+	// variable SCUD_BUILD_MODE=docker.
+	BuildMode BuildMode
+
+	// Container image used by container-based build, default is the golang
+	// image pinned to the version required by the module (go.mod).
+	//	DockerImage: "golang:1.24.3"
+	DockerImage string
+}
+
+// BuildMode defines how the function is compiled
+type BuildMode string
+
+const (
+	// Build the function with local Go toolchain (default),
+	// the failure aborts synthesis.
+	BuildModeLocal BuildMode = "local"
+
+	// Build the function inside container (golang image), module and
+	// GOMODCACHE are mounted into container. Build flags and Go environment
+	// are same as local build.
+	BuildModeDocker BuildMode = "docker"
+)
+
+func validateBuildMode(mode BuildMode) error {
+	switch mode {
+	case "", BuildModeLocal, BuildModeDocker:
+		return nil
+	default:
+		return fmt.Errorf("build mode %q is not supported, use %s or %s", mode, BuildModeLocal, BuildModeDocker)
+	}
 }
 
+// CompileError is returned when Go toolchain fails to build the function
+type CompileError struct {
+	// Package of the function
+	Package string
+
+	// Exit code of `go build`, -1 if the compiler is not started
+	ExitCode int
+
+	// Diagnostic output of the compiler
+	Output string
+
+	Err error
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("go build %s failed (exit code %d): %s\n%s", e.Package, e.ExitCode, e.Err, e.Output)
+}
+
+func (e *CompileError) Unwrap() error { return e.Err }
+
 type GoCompiler struct {
 	sourceCode        string
 	sourceCodePackage string
 	sourceCodeLambda  string
 	sourceCodeVersion string
 	config            *Toolchain
+
+	// asset hash of the function and the build by BuildCoordinator
+	manifest *Manifest
+	build    *goBuild
+
+	// Lambda architecture of the function (arm64 or x86_64), it is derived
+	// from GOARCH unless it is configured by the construct.
+	architecture string
+
+	// Lambda runtime of the function, default is provided.al2023. The runtime
+	// is not validated for container image, the image provides libc.
+	runtime   string
+	container bool
+
+	// report, build information and ELF metadata of the latest build
+	report    *BuildReport
+	buildInfo *debug.BuildInfo
+	binary    *elfBinary
+
+	// results of go list and go env, shared by functions of BuildCoordinator
+	golist *goListCache
 }
 
-const goBinary = "bootstrap"
+const (
+	goBinary  = "bootstrap"
+	goProfile = "default.pgo"
+)
 
+// NewGoCompiler creates compiler of the function, it panics if the toolchain
+// configuration is invalid.
 func NewGoCompiler(
 	sourceCodePackage string,
 	sourceCodeLambda string,
@@ -54,26 +203,246 @@ func NewGoCompiler(
 		}
 	}
 
+	if err := validateToolchain(config); err != nil {
+		panic(fmt.Errorf("invalid toolchain of %s: %w", filepath.Join(sourceCodePackage, sourceCodeLambda), err))
+	}
+
 	return &GoCompiler{
 		sourceCode:        filepath.Join(sourceCodePackage, sourceCodeLambda),
 		sourceCodePackage: sourceCodePackage,
 		sourceCodeLambda:  sourceCodeLambda,
 		sourceCodeVersion: sourceCodeVersion,
 		config:            config,
+		golist:            &goListCache{},
 	}
 }
 
 func (g *GoCompiler) SourceCodeModule() string  { return g.sourceCodePackage }
 func (g *GoCompiler) SourceCodeLambda() string  { return g.sourceCodeLambda }
 func (g *GoCompiler) SourceCodeVersion() string { return g.sourceCodeVersion }
+func (g *GoCompiler) LocalModules() []string    { return g.config.LocalModules }
 
+// TryBundle implements awscdk.ILocalBundling. The function is built either
+// with local Go toolchain or inside container, the failure aborts synthesis
+// with CompileError. The binary built by BuildCoordinator is copied as-is.
+// The binary exceeding the size budget aborts synthesis with BinarySizeError.
 func (g *GoCompiler) TryBundle(outputDir *string, options *awscdk.BundlingOptions) *bool {
 	t := time.Now()
 
-	target := filepath.Join(*outputDir, goBinary)
-	goflags := []string{"build", "-tags", "lambda.norpc"}
+	bundle := g.bundleWithCache
+	if g.build != nil {
+		bundle = g.build.copyTo
+	}
+
+	if err := bundle(*outputDir); err != nil {
+		panic(err)
+	}
+
+	duration := time.Since(t)
+	if g.build != nil {
+		duration = g.build.duration
+	}
+
+	bin, err := g.validateBinary(*outputDir)
+	if err != nil {
+		panic(err)
+	}
+	g.binary = bin
+
+	info, err := readBuildInfo(*outputDir)
+	if err != nil {
+		panic(err)
+	}
+	g.buildInfo = info
+
+	report, err := g.inspect(*outputDir, duration)
+	if err != nil {
+		panic(err)
+	}
+	g.report = report
+
+	return jsii.Bool(true)
+}
+
+// Bundle builds the function binary into the output directory
+func (g *GoCompiler) Bundle(outputDir string) error {
+	t := time.Now()
+
+	root, err := g.moduleDir()
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(outputDir, goBinary)
+	stderr := &bytes.Buffer{}
+
+	var cmd goRunner
+	switch g.buildMode() {
+	case BuildModeDocker:
+		cmd, err = g.dockerCmd(root, outputDir, stderr)
+		if err != nil {
+			return err
+		}
+		target = dockerAssetOutput + "/" + goBinary
+	default:
+		cmd = &localGo{
+			dir:    root,
+			env:    g.cmdEnv(),
+			stdout: os.Stdout,
+			stderr: io.MultiWriter(os.Stderr, stderr),
+		}
+	}
+
+	goflags := append([]string{"build"}, g.BuildFlags()...)
+	goflags = append(goflags, "-o", target)
+	goflags = append(goflags, g.sourceCodeMain())
+
+	if err := cmd.Run(goflags...); err != nil {
+		exitCode := -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+
+		return &CompileError{
+			Package:  g.sourceCode,
+			ExitCode: exitCode,
+			Output:   stderr.String(),
+			Err:      err,
+		}
+	}
+
+	log.Printf("==> go build %s (%v)\n", g.sourceCode, time.Since(t))
+
+	if err := writeBuildInfo(outputDir); err != nil {
+		return err
+	}
+
+	if flags := g.CompressFlags(); len(flags) > 0 {
+		if err := compressUPX(g.sourceCode, filepath.Join(outputDir, goBinary), flags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bundleWithCache copies the binary from the build cache if it is available,
+// otherwise the function is built and the binary is cached. Pre-build checks
+// are executed in both cases.
+func (g *GoCompiler) bundleWithCache(outputDir string) error {
+	if err := g.check(); err != nil {
+		return err
+	}
+
+	cache, err := openBuildCache()
+	if err != nil {
+		return err
+	}
+
+	if cache == nil || g.manifest == nil {
+		return g.Bundle(outputDir)
+	}
+
+	checksum := g.manifest.Checksum
+	hit, err := cache.Get(checksum, outputDir)
+	if err != nil {
+		return err
+	}
+
+	if hit {
+		log.Printf("==> build cache %s | %s\n", checksum[:8], g.sourceCode)
+		return nil
+	}
+
+	if err := g.Bundle(outputDir); err != nil {
+		return err
+	}
+
+	return cache.Put(checksum, outputDir)
+}
+
+// buildMode returns effective build mode of the function
+func (g *GoCompiler) buildMode() BuildMode {
+	if g.config.BuildMode != "" {
+		return g.config.BuildMode
+	}
+
+	if mode := os.Getenv("SCUD_BUILD_MODE"); mode != "" {
+		return BuildMode(mode)
+	}
+
+	return BuildModeLocal
+}
+
+// BuildImage returns container image used to build the function,
+// it is empty if the function is built with local Go toolchain.
+func (g *GoCompiler) BuildImage() string {
+	if g.buildMode() != BuildModeDocker {
+		return ""
+	}
+
+	if g.config.DockerImage != "" {
+		return g.config.DockerImage
+	}
+
+	root, err := g.moduleDir()
+	if err != nil {
+		return "golang"
+	}
+
+	src, err := dockerSourceRoot(root)
+	if err != nil {
+		return "golang"
+	}
+
+	return dockerGoImage(src)
+}
+
+func (g *GoCompiler) dockerCmd(root, outputDir string, stderr io.Writer) (*dockerGo, error) {
+	src, err := dockerSourceRoot(root)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := filepath.Abs(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dockerGo{
+		image:  g.BuildImage(),
+		root:   src,
+		dir:    root,
+		env:    g.cmdEnv(),
+		mounts: map[string]string{output: dockerAssetOutput},
+		stdout: os.Stdout,
+		stderr: io.MultiWriter(os.Stderr, stderr),
+	}, nil
+}
+
+// sourceCodeMain returns path to the main package relative to module
+func (g *GoCompiler) sourceCodeMain() string {
+	return "./" + path.Clean(g.sourceCodeLambda)
+}
+
+// BuildEnv returns effective environment of Go toolchain used to build the function.
+func (g *GoCompiler) BuildEnv() []string { return g.cmdEnv() }
+
+// BuildFlags returns effective flags of `go build` used to build the function,
+// the output and the package are excluded.
+func (g *GoCompiler) BuildFlags() []string {
+	goflags := append([]string{}, g.config.Flags...)
+	goflags = append(goflags, "-tags", strings.Join(g.buildTags(), ","))
+
+	if pgo := g.buildProfile(); pgo != "" {
+		goflags = append(goflags, "-pgo="+pgo)
+	}
 
 	ldflags := []string{"-s", "-w"}
+	if g.config.Cgo != nil {
+		ldflags = append(ldflags, g.config.Cgo.ldflags()...)
+	}
 	if len(g.config.LDFlags) > 0 {
 		ldflags = append(ldflags, g.config.LDFlags...)
 	}
@@ -81,38 +450,90 @@ func (g *GoCompiler) TryBundle(outputDir *string, options *awscdk.BundlingOption
 		ldflags = append(ldflags, fmt.Sprintf("-X main.version=%s", g.sourceCodeVersion))
 	}
 
-	for name, value := range g.config.LDVars {
-		ldflags = append(ldflags, fmt.Sprintf("-X %s=%s", name, value))
+	// LDVars are sorted to keep flags (and the asset hash) deterministic
+	ldvars := make([]string, 0, len(g.config.LDVars))
+	for name := range g.config.LDVars {
+		ldvars = append(ldvars, name)
+	}
+	sort.Strings(ldvars)
+
+	for _, name := range ldvars {
+		ldflags = append(ldflags, fmt.Sprintf("-X %s=%s", name, g.config.LDVars[name]))
 	}
 	goflags = append(goflags, "-ldflags", strings.Join(ldflags, " "))
-	goflags = append(goflags, "-o", target)
-	goflags = append(goflags, filepath.Join(g.sourceCode))
 
-	cmd := exec.Command("go", goflags...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Env = g.cmdEnv()
+	return goflags
+}
+
+// buildTags returns default tags followed by unique sorted tags of toolchain
+func (g *GoCompiler) buildTags() []string {
+	tags := []string{"lambda.norpc"}
 
-	if err := cmd.Run(); err != nil {
-		log.Printf("%s", err)
-		return jsii.Bool(false)
+	seq := append([]string{}, g.config.Tags...)
+	if g.config.Cgo != nil {
+		seq = append(seq, g.config.Cgo.tags()...)
+	}
+	sort.Strings(seq)
+	for _, tag := range seq {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
 	}
 
-	log.Printf("==> go build %s (%v)\n", g.sourceCode, time.Since(t))
+	return tags
+}
+
+// buildProfile returns path to PGO profile relative to the module,
+// it is empty if the function has no profile. PGO is disabled explicitly
+// with "off", otherwise `go build` uses default.pgo of the main package.
+func (g *GoCompiler) buildProfile() string {
+	switch g.config.PGO {
+	case "off":
+		return "off"
+	case "":
+		root, err := g.moduleDir()
+		if err != nil {
+			return ""
+		}
+
+		file := path.Join(path.Clean(g.sourceCodeLambda), goProfile)
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(file))); err != nil {
+			return ""
+		}
+
+		return file
+	default:
+		return path.Clean(filepath.ToSlash(g.config.PGO))
+	}
+}
+
+// CompressFlags returns effective flags of UPX used to compress the binary,
+// it is empty if the binary is not compressed.
+func (g *GoCompiler) CompressFlags() []string {
+	if g.config.Compression != nil {
+		return g.config.Compression.flags()
+	}
 
 	if os.Getenv("SCUD_COMPRESS_UPX") == "1" {
-		t := time.Now()
-		cmd = exec.Command("upx", "--best", "-q", "--lzma", target)
-		cmd.Stdout = io.Discard
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			log.Printf("%s", err)
-			return jsii.Bool(false)
+		c := &Compression{
+			Method:   CompressionUPX,
+			Level:    CompressionLevelBest,
+			Strategy: CompressionStrategyLZMA,
 		}
-		log.Printf("==> compress %s (%v)\n", g.sourceCode, time.Since(t))
+		return c.flags()
 	}
 
-	return jsii.Bool(true)
+	return nil
+}
+
+// goTool returns go command of the function
+func (g *GoCompiler) goTool() goTool {
+	return goTool{mode: g.buildMode(), image: g.config.DockerImage, cache: g.golist}
+}
+
+// moduleDir resolves the directory of the module on the disk
+func (g *GoCompiler) moduleDir() (string, error) {
+	return g.goTool().moduleDir(g.cmdEnv(), g.sourceCodePackage)
 }
 
 func (g *GoCompiler) goCache() string {
@@ -127,38 +548,167 @@ func (g *GoCompiler) goCache() string {
 }
 
 func (g *GoCompiler) cmdEnv() []string {
+	goenv := g.goEnvMap()
+
+	env := make([]string, 0, len(goenv))
+	for key, val := range goenv {
+		env = append(env, key+"="+val)
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+// goenv returns the value of effective Go environment variable
+func (g *GoCompiler) goenv(name string) string {
+	return g.goEnvMap()[name]
+}
+
+func (g *GoCompiler) goEnvMap() map[string]string {
+	goenv := make(map[string]string, len(g.config.GoEnv))
+	for envvar, value := range g.config.GoEnv {
+		goenv[envvar] = value
+	}
+
 	for _, envvar := range []string{
 		"PATH",
 		"GOPATH",
 		"GOROOT",
 		"GOMODCACHE",
+		"GOWORK",
 	} {
-		if _, exists := g.config.GoEnv[envvar]; !exists {
-			g.config.GoEnv[envvar] = os.Getenv(envvar)
+		if _, exists := goenv[envvar]; !exists {
+			goenv[envvar] = os.Getenv(envvar)
 		}
 	}
 
+	cgoEnabled := "0"
+	if g.config.Cgo != nil {
+		cgoEnabled = "1"
+	}
+
 	for envvar, defval := range map[string]string{
 		"GOOS":        "linux",
 		"GOARCH":      "arm64",
-		"CGO_ENABLED": "0",
+		"CGO_ENABLED": cgoEnabled,
 	} {
-		if _, exists := g.config.GoEnv[envvar]; !exists {
-			g.config.GoEnv[envvar] = defval
+		if _, exists := goenv[envvar]; !exists {
+			goenv[envvar] = defval
+		}
+	}
+
+	// C toolchain of cgo cross-compilation, the architecture is validated
+	if g.config.Cgo != nil {
+		cc, _ := g.config.Cgo.env(goenv["GOARCH"])
+		for envvar, value := range cc {
+			if _, exists := goenv[envvar]; !exists {
+				goenv[envvar] = value
+			}
 		}
 	}
 
 	for envvar, gen := range map[string]func() string{
 		"GOCACHE": g.goCache,
 	} {
-		if _, exists := g.config.GoEnv[envvar]; !exists {
-			g.config.GoEnv[envvar] = gen()
+		if _, exists := goenv[envvar]; !exists {
+			goenv[envvar] = gen()
 		}
 	}
 
-	env := make([]string, 0)
-	for key, val := range g.config.GoEnv {
-		env = append(env, key+"="+val)
+	return goenv
+}
+
+// flags of `go build` configurable by Toolchain.Flags, the value defines if
+// the flag requires value (-flag=value).
+var goBuildFlags = map[string]bool{
+	"-a":             false,
+	"-asan":          false,
+	"-buildvcs":      false,
+	"-cover":         false,
+	"-linkshared":    false,
+	"-modcacherw":    false,
+	"-msan":          false,
+	"-race":          false,
+	"-trimpath":      false,
+	"-asmflags":      true,
+	"-buildmode":     true,
+	"-compiler":      true,
+	"-covermode":     true,
+	"-coverpkg":      true,
+	"-gccgoflags":    true,
+	"-gcflags":       true,
+	"-installsuffix": true,
+	"-mod":           true,
+	"-modfile":       true,
+	"-overlay":       true,
+	"-p":             true,
+	"-toolexec":      true,
+}
+
+var goBuildTag = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+func validateToolchain(config *Toolchain) error {
+	if err := validateBuildMode(config.BuildMode); err != nil {
+		return err
 	}
-	return env
+
+	if err := validateBuildMode(BuildMode(os.Getenv("SCUD_BUILD_MODE"))); err != nil {
+		return fmt.Errorf("invalid SCUD_BUILD_MODE: %w", err)
+	}
+
+	for _, tag := range config.Tags {
+		if !goBuildTag.MatchString(tag) {
+			return fmt.Errorf("invalid build tag %q", tag)
+		}
+	}
+
+	if config.PGO != "" && config.PGO != "off" {
+		if filepath.IsAbs(config.PGO) || !filepath.IsLocal(config.PGO) {
+			return fmt.Errorf("PGO profile %s must be relative to the module", config.PGO)
+		}
+	}
+
+	if err := validateCompression(config.Compression); err != nil {
+		return err
+	}
+
+	if err := validateVulncheck(config.Vulncheck); err != nil {
+		return err
+	}
+
+	if err := validateSBOMFormat(config.SBOM); err != nil {
+		return err
+	}
+
+	if err := validateCgo(config); err != nil {
+		return err
+	}
+
+	for _, flag := range config.Flags {
+		name, _, hasValue := strings.Cut(flag, "=")
+		name = "-" + strings.TrimLeft(name, "-")
+
+		switch name {
+		case "-o":
+			return fmt.Errorf("flag %s is not supported, output is defined by scud", flag)
+		case "-tags":
+			return fmt.Errorf("flag %s is not supported, use Toolchain.Tags", flag)
+		case "-ldflags":
+			return fmt.Errorf("flag %s is not supported, use Toolchain.LDFlags or Toolchain.LDVars", flag)
+		case "-pgo":
+			return fmt.Errorf("flag %s is not supported, use Toolchain.PGO", flag)
+		}
+
+		requiresValue, known := goBuildFlags[name]
+		switch {
+		case !strings.HasPrefix(flag, "-"):
+			return fmt.Errorf("invalid flag %q, flag must start with '-'", flag)
+		case !known:
+			return fmt.Errorf("flag %s is not supported", flag)
+		case requiresValue && !hasValue:
+			return fmt.Errorf("flag %s requires value, use %s=value", flag, name)
+		}
+	}
+
+	return nil
 }
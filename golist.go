@@ -0,0 +1,339 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// goPackage is a subset of `go list -json` output required by scud
+type goPackage struct {
+	Dir        string
+	ImportPath string
+	Standard   bool
+	Module     *goModule
+	Deps       []string
+	Error      *goPackageError
+
+	// Source files of the package that are inputs of the compiler,
+	// the path is relative to the package directory.
+	GoFiles      []string
+	CgoFiles     []string
+	CFiles       []string
+	CXXFiles     []string
+	MFiles       []string
+	HFiles       []string
+	FFiles       []string
+	SFiles       []string
+	SwigFiles    []string
+	SwigCXXFiles []string
+	SysoFiles    []string
+	EmbedFiles   []string
+}
+
+// goPackageError is the error of loading the package (`go list -e`)
+type goPackageError struct {
+	Err string
+}
+
+// SourceFiles returns all input files of the package, the path is relative
+// to the package directory.
+func (pkg *goPackage) SourceFiles() []string {
+	seq := []string{}
+	for _, files := range [][]string{
+		pkg.GoFiles,
+		pkg.CgoFiles,
+		pkg.CFiles,
+		pkg.CXXFiles,
+		pkg.MFiles,
+		pkg.HFiles,
+		pkg.FFiles,
+		pkg.SFiles,
+		pkg.SwigFiles,
+		pkg.SwigCXXFiles,
+		pkg.SysoFiles,
+		pkg.EmbedFiles,
+	} {
+		seq = append(seq, files...)
+	}
+	return seq
+}
+
+// RelDir returns directory of the package relative to the root of its module
+// (slash separated).
+func (pkg *goPackage) RelDir() (string, error) {
+	if pkg.Module == nil || pkg.Module.Dir == "" {
+		return "", fmt.Errorf("package %s is not a part of module", pkg.ImportPath)
+	}
+
+	rel, err := filepath.Rel(pkg.Module.Dir, pkg.Dir)
+	if err != nil {
+		return "", err
+	}
+
+	return path.Clean(filepath.ToSlash(rel)), nil
+}
+
+// goModule is a subset of `go list -json` module metadata
+type goModule struct {
+	Path      string
+	Version   string
+	Dir       string
+	GoMod     string
+	GoVersion string
+	Main      bool
+	Replace   *goModule
+}
+
+// goListCache caches results of `go list` and `go env` resolved for functions,
+// the cache is shared by functions of BuildCoordinator. The function created
+// without coordinator owns the cache.
+type goListCache struct {
+	dirs   sync.Map
+	graphs sync.Map
+	envs   sync.Map
+}
+
+// goTool executes go command either on the host or inside golang container
+// (BuildModeDocker), results are cached
+type goTool struct {
+	mode  BuildMode
+	image string
+	cache *goListCache
+}
+
+// key of cached results, it depends on the toolchain
+func (tool goTool) key() string {
+	return string(tool.mode) + "|" + tool.image
+}
+
+// moduleDir resolves the directory of the module on the disk. The module is
+// resolved by Go toolchain (`go list -m`) from the current working directory,
+// it is either the main module, the module of Go workspace or the dependency.
+// The location of the module does not depend on GOPATH or CI environment.
+func (tool goTool) moduleDir(env []string, module string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	key := tool.key() + "|" + cwd + "|" + strings.Join(env, " ") + "|" + module
+	if dir, has := tool.cache.dirs.Load(key); has {
+		return dir.(string), nil
+	}
+
+	stdout, err := tool.command("", env, "list", "-m", "-f", "{{.Dir}}", module)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve module %s: %w", module, err)
+	}
+
+	dir := strings.TrimSpace(string(stdout))
+	if dir == "" {
+		return "", fmt.Errorf("unable to resolve module %s: source code is not available", module)
+	}
+
+	tool.cache.dirs.Store(key, dir)
+	return dir, nil
+}
+
+// goGraph is the graph of packages resolved by `go list -deps` for the build
+// configuration, packages are indexed by import path. Each package of
+// the graph is accompanied by all its dependencies.
+type goGraph struct {
+	sync.Mutex
+	pkgs map[string]*goPackage
+}
+
+func (tool goTool) graphOf(dir string, env, flags []string) *goGraph {
+	key := tool.key() + "|" + dir + "|" + strings.Join(env, " ") + "|" + strings.Join(flags, " ")
+	graph, _ := tool.cache.graphs.LoadOrStore(key, &goGraph{pkgs: map[string]*goPackage{}})
+	return graph.(*goGraph)
+}
+
+// load resolves packages missing in the graph with single `go list` call
+func (g *goGraph) load(tool goTool, dir string, env, flags []string, pkgs []string) error {
+	missing := []string{}
+	for _, pkg := range pkgs {
+		if _, has := g.pkgs[pkg]; !has && !slices.Contains(missing, pkg) {
+			missing = append(missing, pkg)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	args := append([]string{"-e", "-deps"}, flags...)
+	seq, err := tool.listPackages(dir, env, append(args, missing...)...)
+	if err != nil {
+		return err
+	}
+
+	for _, pkg := range seq {
+		g.pkgs[pkg.ImportPath] = pkg
+	}
+
+	return nil
+}
+
+// listDeps resolves packages and their dependencies with single
+// `go list -deps` call, the result is cached for the build configuration.
+func (tool goTool) listDeps(dir string, env, flags []string, pkgs ...string) error {
+	graph := tool.graphOf(dir, env, flags)
+	graph.Lock()
+	defer graph.Unlock()
+
+	return graph.load(tool, dir, env, flags, pkgs)
+}
+
+// packageDeps returns the package followed by all its dependencies,
+// the dependencies are resolved by listDeps unless they are cached.
+func (tool goTool) packageDeps(dir string, env, flags []string, pkg string) ([]*goPackage, error) {
+	graph := tool.graphOf(dir, env, flags)
+	graph.Lock()
+	defer graph.Unlock()
+
+	if err := graph.load(tool, dir, env, flags, []string{pkg}); err != nil {
+		return nil, err
+	}
+
+	main, has := graph.pkgs[pkg]
+	if !has {
+		return nil, fmt.Errorf("package %s is not found", pkg)
+	}
+
+	seq := []*goPackage{main}
+	for _, dep := range main.Deps {
+		if p, has := graph.pkgs[dep]; has {
+			seq = append(seq, p)
+		}
+	}
+
+	for _, p := range seq {
+		if p.Error != nil {
+			return nil, fmt.Errorf("go list %s: %s", p.ImportPath, p.Error.Err)
+		}
+	}
+
+	return seq, nil
+}
+
+// env returns the value of Go environment variable, the value is cached.
+func (tool goTool) env(dir string, env []string, name string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	key := tool.key() + "|" + cwd + "|" + dir + "|" + strings.Join(env, " ") + "|" + name
+	if val, has := tool.cache.envs.Load(key); has {
+		return val.(string), nil
+	}
+
+	stdout, err := tool.command(dir, env, "env", name)
+	if err != nil {
+		return "", err
+	}
+
+	val := strings.TrimSpace(string(stdout))
+	tool.cache.envs.Store(key, val)
+	return val, nil
+}
+
+// listPackages executes `go list -json` within the directory
+func (tool goTool) listPackages(dir string, env []string, args ...string) ([]*goPackage, error) {
+	stdout, err := tool.command(dir, env, append([]string{"list", "-json"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	seq := []*goPackage{}
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var pkg goPackage
+		err := dec.Decode(&pkg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, &pkg)
+	}
+
+	return seq, nil
+}
+
+// command executes go tool within the directory, the error includes tool's
+// diagnostic output.
+func (tool goTool) command(dir string, env []string, args ...string) ([]byte, error) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd, err := tool.runnerAt(dir, env, stdout, stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Run(args...); err != nil {
+		return nil, fmt.Errorf("go %s: %w\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// runnerAt returns go tool executed within the directory, either local
+// toolchain or the toolchain inside golang container.
+func (tool goTool) runnerAt(dir string, env []string, stdout, stderr io.Writer) (goRunner, error) {
+	if tool.mode != BuildModeDocker {
+		return &localGo{dir: dir, env: env, stdout: stdout, stderr: stderr}, nil
+	}
+
+	return goInDocker(tool.image, dir, env, stdout, stderr)
+}
+
+// goInDocker returns go tool inside the container, the image is pinned to
+// the version of Go required by the source code unless it is defined.
+func goInDocker(image, dir string, env []string, stdout, stderr io.Writer) (*dockerGo, error) {
+	if dir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		dir = cwd
+	}
+
+	root, err := dockerSourceRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if image == "" {
+		image = dockerGoImage(root)
+	}
+
+	return &dockerGo{
+		image:  image,
+		root:   root,
+		dir:    dir,
+		env:    env,
+		stdout: stdout,
+		stderr: stderr,
+	}, nil
+}
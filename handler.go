@@ -9,17 +9,20 @@
 package scud
 
 import (
+	"fmt"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
-	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
 	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
 )
 
-// FunctionGoProps is properties of the function
+// FunctionGoProps is properties of the function. The function is deployed to
+// OS-only runtime defined by FunctionProps.Runtime, either provided.al2023
+// (default) or provided.al2.
 type FunctionGoProps struct {
 	*awslambda.FunctionProps
 
@@ -37,6 +40,28 @@ type FunctionGoProps struct {
 
 	// Toolchain configuration for building Go Lambda function
 	Toolchain *Toolchain
+
+	// Compression of the function binary, it overrides the compression
+	// defined by the toolchain.
+	Compression *Compression
+
+	// Logging of the function, it overrides the logging configuration of
+	// the application (NewLogging).
+	Logging *LoggingProps
+
+	// Gradual deployment of the function with CodeDeploy, the function is
+	// published as version and accessed through the alias.
+	Deployment *DeploymentProps
+
+	// Provisioned concurrency of the function, it is allocated to the alias.
+	ProvisionedConcurrency *ProvisionedConcurrencyProps
+
+	// compiler of the function registered with BuildCoordinator, its
+	// toolchain resolved at the scope of registration and the output
+	// directory of the cloud assembly where the asset is staged
+	compiler       *GoCompiler
+	compilerConfig *Toolchain
+	compilerOutdir string
 }
 
 func (*FunctionGoProps) HKT1(awslambda.Function) {}
@@ -45,12 +70,33 @@ func (props *FunctionGoProps) UniqueID() string {
 	return funcName(props.SourceCodeModule, props.SourceCodeLambda)
 }
 
+// toolchain returns toolchain of the function with per-function overrides
+func (props *FunctionGoProps) toolchain() *Toolchain {
+	if props.Compression == nil {
+		return props.Toolchain
+	}
+
+	var config Toolchain
+	if props.Toolchain != nil {
+		config = *props.Toolchain
+	}
+	config.Compression = props.Compression
+
+	return &config
+}
+
 // NewFunctionGo creates Golang Lambda Function from "inline" code
 func NewFunctionGo(scope constructs.Construct, id *string, spec *FunctionGoProps) awslambda.Function {
+	defaults := defaultsOf(scope)
+	config := mergeToolchain(defaults.Toolchain, spec.toolchain())
+
 	var props awslambda.FunctionProps
 	if spec.FunctionProps != nil {
 		props = *spec.FunctionProps
 	}
+	if defaults.FunctionProps != nil {
+		props = mergeFunctionProps(props, *defaults.FunctionProps)
+	}
 
 	if props.Timeout == nil {
 		props.Timeout = awscdk.Duration_Minutes(jsii.Number(1))
@@ -60,21 +106,22 @@ func NewFunctionGo(scope constructs.Construct, id *string, spec *FunctionGoProps
 		props.FunctionName = jsii.Sprintf("%s-%s", *awscdk.Aws_STACK_NAME(), spec.UniqueID())
 	}
 
-	if props.LogGroup == nil {
-		// See: https://docs.aws.amazon.com/lambda/latest/dg/monitoring-cloudwatchlogs-loggroups.html
-		props.LogGroup = awslogs.NewLogGroup(scope, jsii.String("Logs"+spec.UniqueID()),
-			&awslogs.LogGroupProps{
-				LogGroupName:  props.FunctionName,
-				Retention:     awslogs.RetentionDays_FIVE_DAYS,
-				RemovalPolicy: awscdk.RemovalPolicy_DESTROY,
-			},
-		)
+	logging := newFunctionLogging(scope, "Logs"+spec.UniqueID(), props.FunctionName, props.LogGroup, mergeLogging(defaults.Logging, spec.Logging))
+	props.LogGroup = logging.LogGroup
+	if props.LoggingFormat == "" {
+		props.LoggingFormat = logging.Format
+	}
+	if props.ApplicationLogLevelV2 == "" {
+		props.ApplicationLogLevelV2 = logging.ApplicationLogLevel
+	}
+	if props.SystemLogLevelV2 == "" {
+		props.SystemLogLevelV2 = logging.SystemLogLevel
 	}
 
 	// arm64 is default deployment
 	props.Architecture = awslambda.Architecture_ARM_64()
-	if spec.Toolchain != nil && spec.Toolchain.GoEnv != nil {
-		switch spec.Toolchain.GoEnv["GOARCH"] {
+	if config != nil && config.GoEnv != nil {
+		switch config.GoEnv["GOARCH"] {
 		case "amd64":
 			props.Architecture = awslambda.Architecture_X86_64()
 		case "arm64":
@@ -82,17 +129,61 @@ func NewFunctionGo(scope constructs.Construct, id *string, spec *FunctionGoProps
 		}
 	}
 
-	gocc := NewGoCompiler(
-		spec.SourceCodeModule,
-		spec.SourceCodeLambda,
-		spec.SourceCodeVersion,
-		spec.Toolchain,
-	)
-	props.Code = AssetCodeGo(gocc)
+	// the function is built by BuildCoordinator with the toolchain resolved
+	// at the scope of registration, it must match the scope of the function
+	gocc := spec.compiler
+	if gocc != nil && !reflect.DeepEqual(spec.compilerConfig, config) {
+		panic(fmt.Errorf("%s is built by coordinator with toolchain other than defaults of scope %q", spec.UniqueID(), *scope.Node().Path()))
+	}
+
+	if gocc == nil {
+		gocc = NewGoCompiler(
+			spec.SourceCodeModule,
+			spec.SourceCodeLambda,
+			spec.SourceCodeVersion,
+			config,
+		)
+	}
+	gocc.architecture = *props.Architecture.Name()
+
+	if props.Runtime == nil {
+		props.Runtime = awslambda.Runtime_PROVIDED_AL2023()
+	}
+	gocc.runtime = *props.Runtime.Name()
+	if err := validateRuntime(gocc.runtime); err != nil {
+		panic(err)
+	}
+	if err := validateProvisionedConcurrency(spec.ProvisionedConcurrency); err != nil {
+		panic(err)
+	}
+
+	vulncheck(scope, *id, gocc)
+
+	code, manifest := assetCodeGo(gocc)
+	props.Code = code
 	props.Handler = jsii.String(goBinary)
-	props.Runtime = awslambda.Runtime_PROVIDED_AL2()
 
-	return awslambda.NewFunction(scope, id, &props)
+	f := awslambda.NewFunction(scope, id, &props)
+
+	if file := artifactPath(f, ".hash.json"); file != "" {
+		if err := WriteManifest(file, manifest); err != nil {
+			panic(err)
+		}
+	}
+
+	stagedArtifacts(f, gocc)
+	if file := artifactPath(f, ".build.json"); file != "" && gocc.report != nil {
+		if err := WriteBuildReport(file, gocc.report); err != nil {
+			panic(err)
+		}
+	}
+
+	writeSBOM(f, gocc)
+	newDeployment(f, spec.Deployment)
+	newProvisionedConcurrency(f, spec.ProvisionedConcurrency)
+	defaults.tag(f)
+
+	return f
 }
 
 func funcName(scModule, scLambda string) string {
@@ -9,16 +9,15 @@
 package scud
 
 import (
-	"bufio"
-	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"regexp"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 )
@@ -28,144 +27,375 @@ type Hasher struct {
 	sourceCodeModule  string
 	sourceCodeLambda  string
 	sourceCodeVersion string
-	fileType          *regexp.Regexp
+	buildEnv          []string
+	buildFlags        []string
+	localModules      []string
+	buildImage        string
+	compressFlags     []string
+	tool              goTool
 	verbose           bool
 }
 
 func NewHasher(verbose bool) *Hasher {
 	return &Hasher{
-		fileType: regexp.MustCompile(`(.*\.go$)|(.*\.(mod|sum)$)`),
-		verbose:  verbose,
+		verbose: verbose,
 	}
 }
 
+// Hash computes checksum of the function built with default toolchain
 func (h *Hasher) Hash(sourceCodeModule, sourceCodeLambda, sourceCodeVersion string) (string, error) {
-	t := time.Now()
-	h.sourceCodeModule = sourceCodeModule
-	h.sourceCodeLambda = sourceCodeLambda
-	h.sourceCodeVersion = sourceCodeVersion
+	return h.HashCompiler(
+		NewGoCompiler(sourceCodeModule, sourceCodeLambda, sourceCodeVersion, nil),
+	)
+}
 
-	hash := sha256.New()
-	if err := h.hashPackage(hash); err != nil {
+// HashCompiler computes checksum of the function built by the compiler
+func (h *Hasher) HashCompiler(compiler Compiler) (string, error) {
+	manifest, err := h.Manifest(compiler)
+	if err != nil {
 		return "", err
 	}
 
-	seq, err := h.deps()
+	return manifest.Checksum, nil
+}
+
+// Manifest computes checksum of the function, the manifest explains
+// the checksum, it lists every input (configuration, module, file) with
+// its own digest.
+func (h *Hasher) Manifest(compiler Compiler) (*Manifest, error) {
+	t := time.Now()
+	h.setup(compiler)
+
+	manifest := &Manifest{
+		Module: h.sourceCodeModule,
+		Lambda: h.sourceCodeLambda,
+	}
+
+	h.hashPackage(manifest)
+
+	if err := h.hashToolchain(manifest); err != nil {
+		return nil, err
+	}
+
+	seq, ext, err := h.deps()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	for _, lib := range seq {
-		dir := h.rootSourceCode(lib)
-		ent, err := os.ReadDir(dir)
-		if err != nil {
-			return "", err
-		}
-		if err := h.hashSubPackage(hash, dir, ent); err != nil {
-			return "", err
+	if err := h.hashWorkspace(manifest, seq[0].Module.Dir); err != nil {
+		return nil, err
+	}
+
+	for _, mod := range h.modules(seq) {
+		h.hashModule(manifest, mod)
+	}
+
+	for _, mod := range ext {
+		h.hashExternalModule(manifest, mod)
+	}
+
+	for _, pkg := range seq {
+		if err := h.hashSubPackage(manifest, pkg); err != nil {
+			return nil, err
 		}
 	}
 
-	checksum := fmt.Sprintf("%x", hash.Sum(nil))
+	if err := h.hashProfile(manifest, seq[0].Module); err != nil {
+		return nil, err
+	}
 
-	log.Printf("==> checksum %s | %s (%v)\n", checksum[:8], sourceCodeLambda, time.Since(t))
+	manifest.Checksum = manifest.checksum()
+
+	log.Printf("==> checksum %s | %s (%v)\n", manifest.Checksum[:8], h.sourceCodeLambda, time.Since(t))
 	if h.verbose {
-		for i, lib := range seq[1:] {
+		for i, pkg := range seq[1:] {
 			if i == len(seq)-2 {
-				fmt.Fprintf(os.Stderr, "    └─ %s\n", lib)
+				fmt.Fprintf(os.Stderr, "    └─ %s\n", pkg.ImportPath)
 				continue
 			}
-			fmt.Fprintf(os.Stderr, "    ├─ %s\n", lib)
+			fmt.Fprintf(os.Stderr, "    ├─ %s\n", pkg.ImportPath)
 		}
 	}
 
-	return checksum, nil
+	return manifest, nil
 }
 
-func (h *Hasher) rootSourceCode(sourceCodeModule string) string {
-	sourceCode := os.Getenv("GITHUB_WORKSPACE")
-	if sourceCode == "" {
-		sourceCode = filepath.Join(os.Getenv("GOPATH"), "src", sourceCodeModule)
+func (h *Hasher) setup(compiler Compiler) {
+	h.sourceCodeModule = compiler.SourceCodeModule()
+	h.sourceCodeLambda = compiler.SourceCodeLambda()
+	h.sourceCodeVersion = compiler.SourceCodeVersion()
+
+	config, ok := compiler.(CompilerConfig)
+	if !ok {
+		config = NewGoCompiler(h.sourceCodeModule, h.sourceCodeLambda, h.sourceCodeVersion, nil)
 	}
 
-	return sourceCode
+	h.buildEnv = config.BuildEnv()
+	h.buildFlags = config.BuildFlags()
+	h.localModules = config.LocalModules()
+	h.buildImage = config.BuildImage()
+	h.compressFlags = config.CompressFlags()
+
+	// the hasher shares results of go list with the compiler
+	h.tool = goTool{cache: &goListCache{}}
+	if gocc, ok := config.(*GoCompiler); ok {
+		h.tool = gocc.goTool()
+	}
 }
 
-func (h *Hasher) deps() ([]string, error) {
-	pkg := filepath.Join(h.sourceCodeModule, h.sourceCodeLambda)
+// deps returns the lambda package followed by its first-party dependencies
+// (sorted by import path), as they are resolved by Go toolchain for
+// the target build configuration. The package is first-party if it belongs
+// to the module, to the module of Go workspace, to the module replaced with
+// local directory or to one of local modules. Modules of other (third-party)
+// packages are returned as the second value.
+func (h *Hasher) deps() ([]*goPackage, []*goModule, error) {
+	pkg := path.Join(h.sourceCodeModule, h.sourceCodeLambda)
 
-	buf := &bytes.Buffer{}
-	cmd := exec.Command("go", "list", "-f", `{{join .Deps "\n"}}`, pkg)
-	cmd.Stdout = buf
-	cmd.Stderr = os.Stderr
+	dir, err := h.tool.moduleDir(h.buildEnv, h.sourceCodeModule)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if err := cmd.Run(); err != nil {
-		return nil, err
+	pkgs, err := h.tool.packageDeps(dir, h.buildEnv, h.buildFlags, pkg)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	seq := []string{pkg}
-	s := bufio.NewScanner(buf)
-	for s.Scan() {
-		line := s.Text()
-		if strings.HasPrefix(line, h.sourceCodeModule) {
-			seq = append(seq, line)
+	main := pkgs[0]
+	seq := []*goPackage{}
+	ext := []*goPackage{}
+	for _, dep := range pkgs[1:] {
+		switch {
+		case h.isLocalPackage(dep):
+			seq = append(seq, dep)
+		case !dep.Standard && dep.Module != nil:
+			ext = append(ext, dep)
 		}
 	}
 
-	return seq, nil
+	if main.Module == nil {
+		return nil, nil, fmt.Errorf("package %s is not found", pkg)
+	}
+
+	sort.Slice(seq, func(i, j int) bool { return seq[i].ImportPath < seq[j].ImportPath })
+
+	return append([]*goPackage{main}, seq...), h.modules(ext), nil
 }
 
-func (h *Hasher) hashPackage(w io.Writer) error {
-	vsn := ""
-	if h.sourceCodeVersion != "" {
-		vsn = fmt.Sprintf("@%s", h.sourceCodeVersion)
+func (h *Hasher) isLocalPackage(pkg *goPackage) bool {
+	if pkg.Standard || pkg.Module == nil {
+		return false
 	}
 
-	pkg := fmt.Sprintf("package: %s %s%s\n", h.sourceCodeModule, h.sourceCodeLambda, vsn)
+	if pkg.Module.Main || hasPathPrefix(pkg.ImportPath, h.sourceCodeModule) {
+		return true
+	}
 
-	_, err := w.Write([]byte(pkg))
-	if err != nil {
-		return err
+	if pkg.Module.Replace != nil && pkg.Module.Replace.Version == "" {
+		return true
 	}
 
-	return nil
+	for _, prefix := range h.localModules {
+		if hasPathPrefix(pkg.Module.Path, prefix) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (h *Hasher) hashSubPackage(w io.Writer, dir string, ent []os.DirEntry) error {
-	for _, entry := range ent {
-		if entry.IsDir() {
-			continue
+// modules returns modules of packages sorted by path
+func (h *Hasher) modules(seq []*goPackage) []*goModule {
+	unique := map[string]*goModule{}
+	for _, pkg := range seq {
+		unique[pkg.Module.Path] = pkg.Module
+	}
+
+	mods := make([]*goModule, 0, len(unique))
+	for _, mod := range unique {
+		mods = append(mods, mod)
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].Path < mods[j].Path })
+
+	return mods
+}
+
+func hasPathPrefix(s, prefix string) bool {
+	return s == prefix || strings.HasPrefix(s, strings.TrimSuffix(prefix, "/")+"/")
+}
+
+func (h *Hasher) hashPackage(m *Manifest) {
+	m.add(InputPackage, path.Join(h.sourceCodeModule, h.sourceCodeLambda), h.sourceCodeVersion)
+}
+
+// hashToolchain writes the effective build configuration and the version of
+// Go toolchain (C compiler and compression tool). Environment is written in canonical (sorted) form, variables
+// pointing to local paths (e.g. PATH, GOCACHE) are excluded because they do
+// not affect the binary.
+func (h *Hasher) hashToolchain(m *Manifest) error {
+	env := make([]string, 0, len(h.buildEnv))
+	for _, kv := range h.buildEnv {
+		key, _, _ := strings.Cut(kv, "=")
+		if !isLocalGoEnv(key) {
+			env = append(env, kv)
 		}
-		if h.fileType.MatchString(entry.Name()) {
-			if err := h.hashFile(w, filepath.Join(dir, entry.Name())); err != nil {
-				return err
-			}
+	}
+	sort.Strings(env)
+
+	for _, kv := range env {
+		key, val, _ := strings.Cut(kv, "=")
+		m.add(InputEnv, key, val)
+	}
+
+	m.add(InputFlags, "go build", strings.Join(h.buildFlags, " "))
+
+	if len(h.compressFlags) > 0 {
+		version, err := upxVersion()
+		if err != nil {
+			return err
 		}
+
+		m.add(InputFlags, "upx", strings.Join(h.compressFlags, " "))
+		m.add(InputToolchain, "upx", version)
 	}
-	return nil
+
+	// container-based build, the version of Go is pinned by the image
+	if h.buildImage != "" {
+		m.add(InputToolchain, "image", h.buildImage)
+		return nil
+	}
+
+	version, err := h.tool.env("", h.buildEnv, "GOVERSION")
+	if err != nil {
+		return err
+	}
+
+	m.add(InputToolchain, "go", version)
+
+	return h.hashCC(m)
 }
 
-func (h *Hasher) hashFile(w io.Writer, path string) error {
-	f, err := os.Open(path)
+// hashCC writes the version of C compiler if cgo is enabled
+func (h *Hasher) hashCC(m *Manifest) error {
+	if !slices.Contains(h.buildEnv, "CGO_ENABLED=1") {
+		return nil
+	}
+
+	cc, err := h.tool.env("", h.buildEnv, "CC")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	_, err = fmt.Fprintf(w, "<file name=%s>\n", path)
+	version, err := ccVersion(cc)
+	if err != nil {
+		return err
+	}
+
+	m.add(InputToolchain, "cc", version)
+
+	return nil
+}
+
+func isLocalGoEnv(key string) bool {
+	switch key {
+	case "PATH", "HOME", "TMPDIR", "GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "GOWORK":
+		return true
+	default:
+		return false
+	}
+}
+
+// hashWorkspace writes go.work if the module is a part of Go workspace.
+// The go.work.sum is not included, it lists checksums of all dependencies of
+// the workspace, the dependencies are accounted by resolved versions instead.
+func (h *Hasher) hashWorkspace(m *Manifest, dir string) error {
+	file, err := h.tool.env(dir, h.buildEnv, "GOWORK")
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(w, f)
+	if file == "" || file == "off" {
+		return nil
+	}
+
+	return h.hashFile(m, "go.work", file)
+}
+
+// hashModule writes identity of first-party module. Its go.mod and go.sum are
+// not included, adding dependency to the module would change the hash of every
+// function. The dependencies are accounted by resolved versions instead.
+func (h *Hasher) hashModule(m *Manifest, mod *goModule) {
+	m.add(InputModule, mod.Path, "go"+mod.GoVersion)
+}
+
+// hashExternalModule writes the resolved version of the third-party module
+// (and its replacement) used by the function.
+func (h *Hasher) hashExternalModule(m *Manifest, mod *goModule) {
+	if mod.Replace != nil {
+		m.add(InputRequire, mod.Path, fmt.Sprintf("%s => %s@%s", mod.Version, mod.Replace.Path, mod.Replace.Version))
+		return
+	}
+
+	m.add(InputRequire, mod.Path, mod.Version)
+}
+
+// hashSubPackage writes all input files of the package: Go and cgo sources,
+// assembly, syso objects and files embedded with //go:embed directive.
+func (h *Hasher) hashSubPackage(m *Manifest, pkg *goPackage) error {
+	rel, err := pkg.RelDir()
 	if err != nil {
 		return err
 	}
 
-	_, err = fmt.Fprintf(w, "</file>\n")
+	files := pkg.SourceFiles()
+	sort.Strings(files)
+
+	for _, file := range files {
+		name := path.Join(pkg.Module.Path, rel, filepath.ToSlash(file))
+		if err := h.hashFile(m, name, filepath.Join(pkg.Dir, file)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashProfile writes PGO profile used by the build (-pgo flag), the path of
+// the profile is relative to the module.
+func (h *Hasher) hashProfile(m *Manifest, mod *goModule) error {
+	for _, flag := range h.buildFlags {
+		file, has := strings.CutPrefix(flag, "-pgo=")
+		if !has || file == "off" || file == "auto" {
+			continue
+		}
+
+		return h.hashFile(m, path.Join(mod.Path, file), filepath.Join(mod.Dir, filepath.FromSlash(file)))
+	}
+
+	return nil
+}
+
+// hashFile writes digest of the file, the file is identified by canonical
+// name: the path relative to the root of the module prefixed with module path
+// (e.g. github.com/fogfish/scud/handler.go), independent of the location of
+// the module on the disk.
+func (h *Hasher) hashFile(m *Manifest, name, file string) error {
+	f, err := os.Open(file)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+
+	m.Inputs = append(m.Inputs, ManifestInput{
+		Kind:   InputFile,
+		Name:   name,
+		Digest: fmt.Sprintf("%x", hash.Sum(nil)),
+	})
 
 	return nil
 }
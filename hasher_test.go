@@ -0,0 +1,695 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud_test
+
+import (
+	"archive/zip"
+	"debug/buildinfo"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/fogfish/it/v2"
+	"github.com/fogfish/scud"
+)
+
+// fixture copies the test module into the given directory
+func fixture(t *testing.T, name, root string) string {
+	t.Helper()
+
+	if err := os.CopyFS(root, os.DirFS(filepath.Join("testdata", name))); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+func checksum(t *testing.T, root string, config *scud.Toolchain) string {
+	t.Helper()
+
+	return checksumOf(t, root, "example.com/hasher", config)
+}
+
+func checksumOf(t *testing.T, root, module string, config *scud.Toolchain) string {
+	t.Helper()
+
+	t.Chdir(root)
+
+	hash, err := scud.NewHasher(false).HashCompiler(
+		scud.NewGoCompiler(module, "cmd/lambda", "", config),
+	)
+	it.Then(t).Must(it.Nil(err))
+
+	return hash
+}
+
+func update(t *testing.T, file string) {
+	t.Helper()
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0664)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("\n// changed\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func write(t *testing.T, file, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(file, []byte(content), 0664); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fakeUPX installs the stub of upx that appends marker to the binary
+func fakeUPX(t *testing.T) {
+	t.Helper()
+
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1\" = \"--version\" ]; then echo \"upx 4.2.4\"; exit 0; fi\n" +
+		"for file; do :; done\n" +
+		"printf upx >> \"$file\"\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "upx"), []byte(script), 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// fakeGo installs the wrapper of go tool that logs arguments of each call
+// (one call per line) into the returned file.
+func fakeGo(t *testing.T) string {
+	t.Helper()
+
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "go.log")
+	script := "#!/bin/sh\n" +
+		"echo \"$*\" >> " + log + "\n" +
+		"exec " + gobin + " \"$@\"\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "go"), []byte(script), 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// fakeZig installs zig stub, it compiles with host C compiler ignoring
+// the target. The static linking is dropped unless it is required.
+func fakeZig(t *testing.T, static bool) {
+	t.Helper()
+
+	link := "-static) ;;"
+	if static {
+		link = "-static) args+=(\"$1\") ;;"
+	}
+
+	dir := t.TempDir()
+	script := "#!/bin/bash\n" +
+		"case \"$1\" in cc) cc=gcc ;; c++) cc=g++ ;; *) echo \"0.13.0\"; exit 0 ;; esac\n" +
+		"shift\n" +
+		"args=()\n" +
+		"while [ $# -gt 0 ]; do\n" +
+		"  case \"$1\" in -target) shift ;; -Qunused-arguments) ;; " + link + " *) args+=(\"$1\") ;; esac\n" +
+		"  shift\n" +
+		"done\n" +
+		"exec $cc \"${args[@]}\"\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "zig"), []byte(script), 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// cpuProfile writes valid CPU profile usable for PGO
+func cpuProfile(t *testing.T, file string) {
+	t.Helper()
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if err := pprof.StartCPUProfile(f); err != nil {
+		t.Fatal(err)
+	}
+	for n, deadline := 0, time.Now().Add(50*time.Millisecond); time.Now().Before(deadline); n++ {
+		_ = fmt.Sprint(n)
+	}
+	pprof.StopCPUProfile()
+}
+
+// fakeDocker installs docker stub, it logs arguments (one per line) into
+// the returned file and executes the go command on the host within working
+// directory and environment of the container. The environment of the host
+// (e.g. GOFLAGS) is not visible, only host caches and the toolchain are used.
+func fakeDocker(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "docker.log")
+	script := "#!/bin/bash\n" +
+		"printf '%s\\n' \"$@\" > " + log + "\n" +
+		"shift 2\n" +
+		"env=()\n" +
+		"while [ $# -gt 0 ]; do\n" +
+		"  case \"$1\" in\n" +
+		"    -u) shift 2 ;;\n" +
+		"    -v) case \"$2\" in *:/asset-output) out=\"${2%%:/asset-output}\" ;; esac; shift 2 ;;\n" +
+		"    -w) cd \"$2\"; shift 2 ;;\n" +
+		"    -e) case \"$2\" in HOME=*|GOMODCACHE=*|GOCACHE=*) ;; *) env+=(\"$2\") ;; esac; shift 2 ;;\n" +
+		"    *) break ;;\n" +
+		"  esac\n" +
+		"done\n" +
+		"shift\n" +
+		"gocache=$(go env GOCACHE)\n" +
+		"exec env -i PATH=\"$PATH\" HOME=\"$HOME\" GOENV=off GOTOOLCHAIN=local \\\n" +
+		"  GOPATH=\"$GOPATH\" GOMODCACHE=\"$GOMODCACHE\" GOCACHE=\"$gocache\" \\\n" +
+		"  \"${env[@]}\" \"${@//\\/asset-output/$out}\"\n"
+
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// goproxy publishes versions of the module from testdata/thirdparty through
+// file-based module proxy, the module is downloaded into private module cache.
+// It returns the module cache.
+func goproxy(t *testing.T, module string, versions ...string) string {
+	t.Helper()
+
+	src := filepath.Join("testdata", "thirdparty", filepath.Base(module))
+	root := t.TempDir()
+	proxy := filepath.Join(root, module, "@v")
+	if err := os.MkdirAll(proxy, 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	gomod, err := os.ReadFile(filepath.Join(src, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, version := range versions {
+		write(t, filepath.Join(proxy, version+".info"), `{"Version":"`+version+`","Time":"2025-01-01T00:00:00Z"}`)
+		write(t, filepath.Join(proxy, version+".mod"), string(gomod))
+		zipModule(t, src, module+"@"+version, filepath.Join(proxy, version+".zip"))
+	}
+
+	cache := t.TempDir()
+	t.Cleanup(func() {
+		// the module cache is read-only, it is removed by go tool
+		cmd := exec.Command("go", "clean", "-modcache")
+		cmd.Env = append(os.Environ(), "GOMODCACHE="+cache, "GOFLAGS=")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("go clean -modcache: %s\n%s", err, out)
+		}
+	})
+
+	t.Setenv("GOPROXY", "file://"+root)
+	t.Setenv("GOMODCACHE", cache)
+	t.Setenv("GOSUMDB", "off")
+
+	return cache
+}
+
+// gomodTidy resolves requirements of the module, it updates go.sum
+func gomodTidy(t *testing.T, dir string) {
+	t.Helper()
+
+	cmd := exec.Command("go", "mod", "tidy")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go mod tidy: %s\n%s", err, out)
+	}
+}
+
+// zipModule packs the module source into zip file of the module proxy
+func zipModule(t *testing.T, src, prefix, file string) {
+	t.Helper()
+
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	defer w.Close()
+
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		entry, err := w.Create(prefix + "/" + filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		_, err = entry.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHasherReproducible(t *testing.T) {
+	a := fixture(t, "hasher", filepath.Join(t.TempDir(), "home", "me", "go", "src", "example.com", "hasher"))
+	b := fixture(t, "hasher", filepath.Join(t.TempDir(), "runner", "work", "hasher"))
+
+	it.Then(t).Should(
+		it.Equal(checksum(t, a, nil), checksum(t, b, nil)),
+		it.Equal(checksum(t, a, nil), checksum(t, a, nil)),
+	)
+}
+
+// sourceCode is Compiler without build configuration (CompilerConfig)
+type sourceCode struct{ module, lambda string }
+
+func (sourceCode) TryBundle(*string, *awscdk.BundlingOptions) *bool { return jsii.Bool(false) }
+func (s sourceCode) SourceCodeModule() string                       { return s.module }
+func (s sourceCode) SourceCodeLambda() string                       { return s.lambda }
+func (sourceCode) SourceCodeVersion() string                        { return "" }
+
+func TestHasherCompiler(t *testing.T) {
+	root := fixture(t, "hasher", t.TempDir())
+	expect := checksum(t, root, nil)
+
+	hash, err := scud.NewHasher(false).Hash("example.com/hasher", "cmd/lambda", "")
+	it.Then(t).Must(it.Nil(err))
+
+	custom, err := scud.NewHasher(false).HashCompiler(sourceCode{"example.com/hasher", "cmd/lambda"})
+	it.Then(t).Must(it.Nil(err))
+
+	it.Then(t).Should(
+		it.Equal(hash, expect),
+		it.Equal(custom, expect),
+	)
+}
+
+func TestHasherSourceCode(t *testing.T) {
+	for file, expect := range map[string]bool{
+		"internal/core/core.go":   true,
+		"internal/core/hello.txt": true,
+		"internal/core/README.md": false,
+	} {
+		t.Run(file, func(t *testing.T) {
+			root := fixture(t, "hasher", t.TempDir())
+			before := checksum(t, root, nil)
+
+			update(t, filepath.Join(root, file))
+
+			it.Then(t).Should(
+				it.Equal(before != checksum(t, root, nil), expect),
+			)
+		})
+	}
+}
+
+func TestHasherToolchain(t *testing.T) {
+	root := fixture(t, "hasher", t.TempDir())
+
+	t.Run("GoEnv", func(t *testing.T) {
+		arm64 := checksum(t, root, &scud.Toolchain{GoEnv: map[string]string{"GOARCH": "arm64"}})
+		amd64 := checksum(t, root, &scud.Toolchain{GoEnv: map[string]string{"GOARCH": "amd64"}})
+
+		it.Then(t).Should(
+			it.Equal(arm64, checksum(t, root, nil)),
+		).ShouldNot(
+			it.Equal(arm64, amd64),
+		)
+	})
+
+	t.Run("LDVars", func(t *testing.T) {
+		a := checksum(t, root, &scud.Toolchain{LDVars: map[string]string{"main.a": "1", "main.b": "2", "main.c": "3"}})
+		b := checksum(t, root, &scud.Toolchain{LDVars: map[string]string{"main.c": "3", "main.b": "2", "main.a": "1"}})
+		c := checksum(t, root, &scud.Toolchain{LDVars: map[string]string{"main.a": "1", "main.b": "2", "main.c": "4"}})
+
+		it.Then(t).Should(
+			it.Equal(a, b),
+		).ShouldNot(
+			it.Equal(a, c),
+		)
+	})
+
+	t.Run("LocalEnv", func(t *testing.T) {
+		a := checksum(t, root, &scud.Toolchain{GoEnv: map[string]string{"GOCACHE": filepath.Join(t.TempDir(), "a")}})
+		b := checksum(t, root, &scud.Toolchain{GoEnv: map[string]string{"GOCACHE": filepath.Join(t.TempDir(), "b")}})
+
+		it.Then(t).Should(
+			it.Equal(a, b),
+		)
+	})
+
+	t.Run("Tags", func(t *testing.T) {
+		a := checksum(t, root, &scud.Toolchain{Tags: []string{"a", "b"}})
+		b := checksum(t, root, &scud.Toolchain{Tags: []string{"b", "a", "lambda.norpc"}})
+		c := checksum(t, root, &scud.Toolchain{Tags: []string{"a"}})
+
+		it.Then(t).Should(
+			it.Equal(a, b),
+		).ShouldNot(
+			it.Equal(a, c),
+		)
+	})
+
+	t.Run("Flags", func(t *testing.T) {
+		a := checksum(t, root, &scud.Toolchain{Flags: []string{"-trimpath"}})
+		b := checksum(t, root, &scud.Toolchain{Flags: []string{"-trimpath", "-buildvcs=false"}})
+		c := checksum(t, root, &scud.Toolchain{Flags: []string{"-trimpath", "-buildvcs"}})
+
+		it.Then(t).ShouldNot(
+			it.Equal(a, b),
+			it.Equal(b, c),
+			it.Equal(a, checksum(t, root, nil)),
+		)
+	})
+
+	t.Run("Compression", func(t *testing.T) {
+		fakeUPX(t)
+
+		none := checksum(t, root, &scud.Toolchain{Compression: &scud.Compression{Method: scud.CompressionNone}})
+		fast := checksum(t, root, &scud.Toolchain{Compression: &scud.Compression{Method: scud.CompressionUPX, Level: 1}})
+		best := checksum(t, root, &scud.Toolchain{Compression: &scud.Compression{Method: scud.CompressionUPX, Level: scud.CompressionLevelBest}})
+
+		it.Then(t).Should(
+			it.Equal(none, checksum(t, root, nil)),
+		).ShouldNot(
+			it.Equal(none, fast),
+			it.Equal(fast, best),
+		)
+	})
+
+	t.Run("DockerImage", func(t *testing.T) {
+		fakeDocker(t)
+
+		a := checksum(t, root, &scud.Toolchain{BuildMode: scud.BuildModeDocker, DockerImage: "golang:1.24.3"})
+		b := checksum(t, root, &scud.Toolchain{BuildMode: scud.BuildModeDocker, DockerImage: "golang:1.24.4"})
+
+		it.Then(t).ShouldNot(
+			it.Equal(a, b),
+			it.Equal(a, checksum(t, root, nil)),
+		)
+	})
+}
+
+func TestHasherBuildTags(t *testing.T) {
+	root := fixture(t, "hasher", t.TempDir())
+	config := &scud.Toolchain{Tags: []string{"dynamodb"}}
+	before, beforeWithTag := checksum(t, root, nil), checksum(t, root, config)
+
+	update(t, filepath.Join(root, "internal/core/storage_dynamodb.go"))
+
+	it.Then(t).Should(
+		it.Equal(before, checksum(t, root, nil)),
+	).ShouldNot(
+		it.Equal(beforeWithTag, checksum(t, root, config)),
+	)
+}
+
+func TestHasherProfile(t *testing.T) {
+	for file, config := range map[string]*scud.Toolchain{
+		"cmd/lambda/default.pgo": nil,
+		"profiles/cpu.pprof":     {PGO: "profiles/cpu.pprof"},
+	} {
+		t.Run(file, func(t *testing.T) {
+			root := fixture(t, "hasher", t.TempDir())
+			without := checksum(t, root, nil)
+			profile := filepath.Join(root, file)
+			if err := os.MkdirAll(filepath.Dir(profile), 0775); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(profile, []byte("profile"), 0664); err != nil {
+				t.Fatal(err)
+			}
+			before := checksum(t, root, config)
+
+			update(t, profile)
+
+			it.Then(t).ShouldNot(
+				it.Equal(without, before),
+				it.Equal(before, checksum(t, root, config)),
+			)
+		})
+	}
+
+	t.Run("off", func(t *testing.T) {
+		root := fixture(t, "hasher", t.TempDir())
+		t.Chdir(root)
+		cpuProfile(t, filepath.Join(root, "cmd/lambda/default.pgo"))
+
+		// the profile is used by the build unless it is disabled explicitly
+		build := func(config *scud.Toolchain) (*scud.GoCompiler, string) {
+			gocc := scud.NewGoCompiler("example.com/hasher", "cmd/lambda", "", config)
+			out := t.TempDir()
+			it.Then(t).Must(it.Nil(gocc.Bundle(out)))
+
+			info, err := buildinfo.ReadFile(filepath.Join(out, "bootstrap"))
+			it.Then(t).Must(it.Nil(err))
+
+			for _, s := range info.Settings {
+				if s.Key == "-pgo" {
+					return gocc, s.Value
+				}
+			}
+			return gocc, ""
+		}
+
+		auto, profile := build(nil)
+		off, disabled := build(&scud.Toolchain{PGO: "off"})
+
+		it.Then(t).Should(
+			it.Seq(auto.BuildFlags()).Contain("-pgo=cmd/lambda/default.pgo"),
+			it.String(profile).HaveSuffix("default.pgo"),
+			it.Seq(off.BuildFlags()).Contain("-pgo=off"),
+			it.Equal(disabled, ""),
+		)
+
+		before := checksum(t, root, &scud.Toolchain{PGO: "off"})
+		update(t, filepath.Join(root, "cmd/lambda/default.pgo"))
+		it.Then(t).Should(
+			it.Equal(before, checksum(t, root, &scud.Toolchain{PGO: "off"})),
+		).ShouldNot(
+			it.Equal(before, checksum(t, root, nil)),
+		)
+	})
+}
+
+func TestToolchainInvalid(t *testing.T) {
+	for _, config := range []*scud.Toolchain{
+		{Tags: []string{"a,b"}},
+		{Flags: []string{"-o=bin"}},
+		{Flags: []string{"-tags=a"}},
+		{Flags: []string{"-ldflags=-s"}},
+		{Flags: []string{"-gcflags"}},
+		{Flags: []string{"trimpath"}},
+		{Flags: []string{"-unknown"}},
+		{Flags: []string{"-pgo=default.pgo"}},
+		{PGO: "/profiles/cpu.pprof"},
+		{PGO: "../cpu.pprof"},
+		{Compression: &scud.Compression{Method: "gzip"}},
+		{Compression: &scud.Compression{Method: scud.CompressionUPX, Level: 11}},
+		{Compression: &scud.Compression{Method: scud.CompressionUPX, Strategy: "zstd"}},
+		{Cgo: &scud.Cgo{Compiler: "clang"}},
+		{Cgo: &scud.Cgo{}, GoEnv: map[string]string{"GOARCH": "386"}},
+		{Cgo: &scud.Cgo{}, GoEnv: map[string]string{"CGO_ENABLED": "0"}},
+		{Cgo: &scud.Cgo{}, BuildMode: scud.BuildModeDocker},
+		{BuildMode: "Docker"},
+		{Vulncheck: &scud.Vulncheck{Unknown: "NONE"}},
+	} {
+		t.Run(fmt.Sprintf("%v%v%s%v", config.Tags, config.Flags, config.PGO, config.Compression), func(t *testing.T) {
+			defer func() {
+				it.Then(t).ShouldNot(it.Nil(recover()))
+			}()
+
+			scud.NewGoCompiler("example.com/hasher", "cmd/lambda", "", config)
+		})
+	}
+
+	t.Run("SCUD_BUILD_MODE", func(t *testing.T) {
+		defer func() {
+			it.Then(t).ShouldNot(it.Nil(recover()))
+		}()
+
+		t.Setenv("SCUD_BUILD_MODE", "Docker")
+		scud.NewGoCompiler("example.com/hasher", "cmd/lambda", "", nil)
+	})
+}
+
+func TestHasherWorkspace(t *testing.T) {
+	for _, file := range []string{"lib/lib.go", "go.work"} {
+		t.Run(file, func(t *testing.T) {
+			root := fixture(t, "workspace", t.TempDir())
+			app := filepath.Join(root, "app")
+			before := checksumOf(t, app, "example.com/app", nil)
+
+			update(t, filepath.Join(root, file))
+
+			it.Then(t).ShouldNot(
+				it.Equal(before, checksumOf(t, app, "example.com/app", nil)),
+			)
+		})
+	}
+}
+
+func TestHasherLocalModules(t *testing.T) {
+	cache := goproxy(t, "example.com/lib", "v1.0.0")
+	root := fixture(t, "thirdparty", t.TempDir())
+	app := filepath.Join(root, "app")
+	gomodTidy(t, app)
+
+	// example.com/lib is the dependency resolved from module cache, it is
+	// first-party only because of LocalModules
+	local := &scud.Toolchain{LocalModules: []string{"example.com/lib"}}
+	before := checksumOf(t, app, "example.com/app", local)
+	third := checksumOf(t, app, "example.com/app", nil)
+
+	lib := filepath.Join(cache, "example.com", "lib@v1.0.0")
+	it.Then(t).Must(it.Nil(os.Chmod(lib, 0775)))
+	it.Then(t).Must(it.Nil(os.Chmod(filepath.Join(lib, "lib.go"), 0664)))
+	update(t, filepath.Join(lib, "lib.go"))
+
+	it.Then(t).ShouldNot(
+		it.Equal(before, checksumOf(t, app, "example.com/app", local)),
+	)
+	it.Then(t).Should(
+		it.Equal(third, checksumOf(t, app, "example.com/app", nil)),
+	)
+}
+
+func TestHasherReplace(t *testing.T) {
+	root := fixture(t, "replace", t.TempDir())
+	app := filepath.Join(root, "app")
+	before := checksumOf(t, app, "example.com/app", nil)
+
+	update(t, filepath.Join(root, "lib", "lib.go"))
+
+	it.Then(t).ShouldNot(
+		it.Equal(before, checksumOf(t, app, "example.com/app", nil)),
+	)
+}
+
+func TestHasherDependencies(t *testing.T) {
+	root := fixture(t, "hasher", t.TempDir())
+
+	gosum := func(version string) {
+		sum := "example.com/unused " + version + "/go.mod h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n"
+		if err := os.WriteFile(filepath.Join(root, "go.sum"), []byte(sum), 0664); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gosum("v1.0.0")
+	before := checksum(t, root, nil)
+
+	gosum("v1.1.0")
+	update(t, filepath.Join(root, "go.mod"))
+
+	it.Then(t).Should(
+		it.Equal(before, checksum(t, root, nil)),
+	)
+}
+
+func TestHasherDependencyVersion(t *testing.T) {
+	goproxy(t, "example.com/lib", "v1.0.0", "v1.1.0")
+
+	app := filepath.Join(fixture(t, "thirdparty", t.TempDir()), "app")
+	gomod, err := os.ReadFile(filepath.Join(app, "go.mod"))
+	it.Then(t).Must(it.Nil(err))
+
+	require := func(version string) {
+		write(t, filepath.Join(app, "go.mod"), strings.ReplaceAll(string(gomod), "v1.0.0", version))
+		gomodTidy(t, app)
+	}
+
+	require("v1.0.0")
+	before := checksumOf(t, app, "example.com/app", nil)
+
+	require("v1.0.0")
+	it.Then(t).Should(
+		it.Equal(before, checksumOf(t, app, "example.com/app", nil)),
+	)
+
+	require("v1.1.0")
+	it.Then(t).ShouldNot(
+		it.Equal(before, checksumOf(t, app, "example.com/app", nil)),
+	)
+}
+
+func TestHasherManifest(t *testing.T) {
+	root := fixture(t, "hasher", t.TempDir())
+	t.Chdir(root)
+
+	manifest := func(config *scud.Toolchain) *scud.Manifest {
+		m, err := scud.NewHasher(false).Manifest(
+			scud.NewGoCompiler("example.com/hasher", "cmd/lambda", "", config),
+		)
+		it.Then(t).Must(it.Nil(err))
+		return m
+	}
+
+	before := manifest(nil)
+	update(t, filepath.Join(root, "internal", "core", "hello.txt"))
+	after := manifest(&scud.Toolchain{GoEnv: map[string]string{"GOARCH": "amd64"}})
+
+	file := filepath.Join(t.TempDir(), "manifest.json")
+	it.Then(t).Must(it.Nil(scud.WriteManifest(file, before)))
+	stored, err := scud.ReadManifest(file)
+	it.Then(t).Must(it.Nil(err))
+
+	diff := scud.DiffManifest(stored, after)
+	it.Then(t).Should(
+		it.Equal(stored.Checksum, before.Checksum),
+		it.Equal(after.Checksum, checksum(t, root, &scud.Toolchain{GoEnv: map[string]string{"GOARCH": "amd64"}})),
+		it.Equal(len(diff), 2),
+		it.Equal(diff[0].Kind, scud.InputEnv),
+		it.Equal(diff[0].Name, "GOARCH"),
+		it.Equal(diff[0].After.Value, "amd64"),
+		it.Equal(diff[1].Kind, scud.InputFile),
+		it.Equal(diff[1].Name, "example.com/hasher/internal/core/hello.txt"),
+	)
+}
@@ -0,0 +1,224 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-cdk-go/awscdk/v2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awskms"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// LoggingProps configures logging of functions. The application-level
+// configuration (NewLogging) is used by every scud function created beneath
+// the scope, the function overrides it field by field (FunctionGoProps.Logging).
+type LoggingProps struct {
+	// Create the log group shared by all functions of the application,
+	// each function has own log group by default. The field is used only by
+	// the application-level configuration.
+	Shared bool
+
+	// Name of the shared log group, the log group of the function is named
+	// after the function.
+	LogGroupName *string
+
+	// Retention of logs, default is 5 days.
+	Retention awslogs.RetentionDays
+
+	// Removal policy of log group, default is destroy.
+	RemovalPolicy awscdk.RemovalPolicy
+
+	// KMS key used to encrypt logs, the key policy must allow CloudWatch Logs.
+	EncryptionKey awskms.IKey
+
+	// Format of logs (TEXT or JSON), JSON is default if log levels are defined.
+	Format awslambda.LoggingFormat
+
+	// Level of application logs, it requires JSON format.
+	ApplicationLogLevel awslambda.ApplicationLogLevel
+
+	// Level of Lambda system logs, it requires JSON format.
+	SystemLogLevel awslambda.SystemLogLevel
+}
+
+// merge returns the configuration overridden by non-zero fields of other one
+func (props LoggingProps) merge(override *LoggingProps) LoggingProps {
+	if override == nil {
+		return props
+	}
+
+	if override.LogGroupName != nil {
+		props.LogGroupName = override.LogGroupName
+	}
+	if override.Retention != "" {
+		props.Retention = override.Retention
+	}
+	if override.RemovalPolicy != "" {
+		props.RemovalPolicy = override.RemovalPolicy
+	}
+	if override.EncryptionKey != nil {
+		props.EncryptionKey = override.EncryptionKey
+	}
+	if override.Format != "" {
+		props.Format = override.Format
+	}
+	if override.ApplicationLogLevel != "" {
+		props.ApplicationLogLevel = override.ApplicationLogLevel
+	}
+	if override.SystemLogLevel != "" {
+		props.SystemLogLevel = override.SystemLogLevel
+	}
+
+	return props
+}
+
+// logGroupProps returns properties of log group with defaults
+func (props LoggingProps) logGroupProps(name *string) *awslogs.LogGroupProps {
+	retention := props.Retention
+	if retention == "" {
+		retention = awslogs.RetentionDays_FIVE_DAYS
+	}
+
+	removalPolicy := props.RemovalPolicy
+	if removalPolicy == "" {
+		removalPolicy = awscdk.RemovalPolicy_DESTROY
+	}
+
+	return &awslogs.LogGroupProps{
+		LogGroupName:  name,
+		Retention:     retention,
+		RemovalPolicy: removalPolicy,
+		EncryptionKey: props.EncryptionKey,
+	}
+}
+
+// format returns format of logs, log levels require JSON format
+func (props LoggingProps) format() (awslambda.LoggingFormat, error) {
+	if props.ApplicationLogLevel == "" && props.SystemLogLevel == "" {
+		return props.Format, nil
+	}
+
+	switch props.Format {
+	case "", awslambda.LoggingFormat_JSON:
+		return awslambda.LoggingFormat_JSON, nil
+	default:
+		return "", fmt.Errorf("log levels require JSON format of logs, format %s is defined", props.Format)
+	}
+}
+
+// Logging is the logging configuration of the application, it is used by
+// every scud function created beneath the scope of the construct.
+type Logging struct {
+	constructs.Construct
+
+	// Log group shared by functions, nil if it is not enabled
+	LogGroup awslogs.ILogGroup
+
+	props *LoggingProps
+}
+
+// NewLogging defines logging configuration of the application, the scope
+// has at most one logging configuration.
+func NewLogging(scope constructs.Construct, id *string, props *LoggingProps) *Logging {
+	if props == nil {
+		props = &LoggingProps{}
+	}
+
+	if _, err := props.format(); err != nil {
+		panic(err)
+	}
+
+	if loggingAt(scope) != nil {
+		panic(fmt.Errorf("logging is already defined at scope %q", *scope.Node().Path()))
+	}
+
+	// the construct is created with override so that the construct tree
+	// refers to *Logging, it is resolved by loggingOf.
+	logging := &Logging{props: props}
+	constructs.NewConstruct_Override(logging, scope, id)
+
+	if props.Shared {
+		logging.LogGroup = awslogs.NewLogGroup(logging.Construct, jsii.String("LogGroup"),
+			props.logGroupProps(props.LogGroupName),
+		)
+	}
+
+	return logging
+}
+
+// Props returns the logging configuration
+func (logging *Logging) Props() *LoggingProps { return logging.props }
+
+// loggingAt returns logging configuration defined at the scope
+func loggingAt(scope constructs.IConstruct) *Logging {
+	for _, child := range *scope.Node().Children() {
+		if logging, ok := child.(*Logging); ok {
+			return logging
+		}
+	}
+
+	return nil
+}
+
+// loggingOf returns logging configuration of the nearest scope
+func loggingOf(scope constructs.Construct) *Logging {
+	var node constructs.IConstruct = scope
+	for ; node != nil; node = node.Node().Scope() {
+		if logging := loggingAt(node); logging != nil {
+			return logging
+		}
+	}
+
+	return nil
+}
+
+// functionLogging is the logging configuration of the function
+type functionLogging struct {
+	LogGroup            awslogs.ILogGroup
+	Format              awslambda.LoggingFormat
+	ApplicationLogLevel awslambda.ApplicationLogLevel
+	SystemLogLevel      awslambda.SystemLogLevel
+}
+
+// newFunctionLogging resolves logging configuration of the function. The log
+// group is either shared by the application or it is created for the function.
+// See: https://docs.aws.amazon.com/lambda/latest/dg/monitoring-cloudwatchlogs-loggroups.html
+func newFunctionLogging(scope constructs.Construct, id string, name *string, logGroup awslogs.ILogGroup, override *LoggingProps) *functionLogging {
+	var props LoggingProps
+
+	logging := loggingOf(scope)
+	if logging != nil {
+		props = *logging.props
+	}
+	props = props.merge(override)
+
+	format, err := props.format()
+	if err != nil {
+		panic(err)
+	}
+
+	switch {
+	case logGroup != nil:
+	case logging != nil && logging.LogGroup != nil:
+		logGroup = logging.LogGroup
+	default:
+		logGroup = awslogs.NewLogGroup(scope, jsii.String(id), props.logGroupProps(name))
+	}
+
+	return &functionLogging{
+		LogGroup:            logGroup,
+		Format:              format,
+		ApplicationLogLevel: props.ApplicationLogLevel,
+		SystemLogLevel:      props.SystemLogLevel,
+	}
+}
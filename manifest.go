@@ -0,0 +1,145 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Kinds of inputs of the asset hash
+const (
+	InputPackage   = "package"
+	InputEnv       = "env"
+	InputFlags     = "flags"
+	InputToolchain = "toolchain"
+	InputModule    = "module"
+	InputRequire   = "require"
+	InputFile      = "file"
+)
+
+// Manifest explains the asset hash of the function. It lists every input
+// of the hash (configuration, module, dependency, file) with its own digest.
+type Manifest struct {
+	Module   string          `json:"module"`
+	Lambda   string          `json:"lambda"`
+	Checksum string          `json:"checksum"`
+	Inputs   []ManifestInput `json:"inputs"`
+}
+
+// ManifestInput is an individual input of the asset hash
+type ManifestInput struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Digest string `json:"digest"`
+}
+
+func (m *Manifest) add(kind, name, value string) {
+	m.Inputs = append(m.Inputs, ManifestInput{
+		Kind:   kind,
+		Name:   name,
+		Value:  value,
+		Digest: fmt.Sprintf("%x", sha256.Sum256([]byte(value))),
+	})
+}
+
+// checksum of the manifest is computed over digests of inputs
+func (m *Manifest) checksum() string {
+	hash := sha256.New()
+	for _, in := range m.Inputs {
+		fmt.Fprintf(hash, "%s %s %s\n", in.Kind, in.Name, in.Digest)
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// WriteManifest persists the manifest as JSON file
+func WriteManifest(file string, m *Manifest) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0775); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b, 0664)
+}
+
+// ReadManifest reads the manifest from JSON file
+func ReadManifest(file string) (*Manifest, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", file, err)
+	}
+
+	return &m, nil
+}
+
+// ManifestChange is the difference of the input between two manifests.
+// Before is nil if the input is added, After is nil if the input is removed.
+type ManifestChange struct {
+	Kind   string
+	Name   string
+	Before *ManifestInput
+	After  *ManifestInput
+}
+
+// DiffManifest explains why the asset hash has been changed, it returns
+// inputs that differ between manifests, sorted by kind and name.
+func DiffManifest(before, after *Manifest) []ManifestChange {
+	type key struct{ kind, name string }
+
+	index := func(m *Manifest) map[key]*ManifestInput {
+		idx := map[key]*ManifestInput{}
+		for i := range m.Inputs {
+			in := &m.Inputs[i]
+			idx[key{in.Kind, in.Name}] = in
+		}
+		return idx
+	}
+
+	a, b := index(before), index(after)
+
+	seq := []ManifestChange{}
+	for k, x := range a {
+		y, has := b[k]
+		switch {
+		case !has:
+			seq = append(seq, ManifestChange{Kind: k.kind, Name: k.name, Before: x})
+		case x.Digest != y.Digest:
+			seq = append(seq, ManifestChange{Kind: k.kind, Name: k.name, Before: x, After: y})
+		}
+	}
+
+	for k, y := range b {
+		if _, has := a[k]; !has {
+			seq = append(seq, ManifestChange{Kind: k.kind, Name: k.name, After: y})
+		}
+	}
+
+	sort.Slice(seq, func(i, j int) bool {
+		if seq[i].Kind != seq[j].Kind {
+			return seq[i].Kind < seq[j].Kind
+		}
+		return seq[i].Name < seq[j].Name
+	})
+
+	return seq
+}
@@ -0,0 +1,181 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BuildReport describes the binary of the function produced by the build
+type BuildReport struct {
+	// Package of the function
+	Package string `json:"package"`
+
+	// Asset hash of the function
+	Checksum string `json:"checksum,omitempty"`
+
+	// Version of Go toolchain (or the container image) used by the build
+	GoVersion string `json:"goVersion"`
+
+	// Size of the binary (bootstrap) in bytes
+	Size int64 `json:"size"`
+
+	// Size of the zipped asset in bytes
+	ZipSize int64 `json:"zipSize"`
+
+	// Size budget of the binary, 0 if the budget is not defined
+	MaxSize int64 `json:"maxSize,omitempty"`
+
+	// Duration of the build in milliseconds
+	Duration int64 `json:"durationMs"`
+}
+
+// BinarySizeError is returned when the binary exceeds the size budget
+type BinarySizeError struct {
+	Package string
+	Size    int64
+	MaxSize int64
+}
+
+func (e *BinarySizeError) Error() string {
+	return fmt.Sprintf("binary of %s exceeds size budget: %s > %s (%d > %d bytes)",
+		e.Package, formatSize(e.Size), formatSize(e.MaxSize), e.Size, e.MaxSize)
+}
+
+// WriteBuildReport persists the report as JSON file
+func WriteBuildReport(file string, r *BuildReport) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0775); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b, 0664)
+}
+
+// ReadBuildReport reads the report from JSON file
+func ReadBuildReport(file string) (*BuildReport, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var r BuildReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("invalid build report %s: %w", file, err)
+	}
+
+	return &r, nil
+}
+
+// inspect reports the binary of the function built into the output directory,
+// it fails if the binary exceeds the size budget.
+func (g *GoCompiler) inspect(outputDir string, duration time.Duration) (*BuildReport, error) {
+	fi, err := os.Stat(filepath.Join(outputDir, goBinary))
+	if err != nil {
+		return nil, err
+	}
+
+	zipSize, err := zipSize(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BuildReport{
+		Package:  g.sourceCode,
+		Size:     fi.Size(),
+		ZipSize:  zipSize,
+		Duration: duration.Milliseconds(),
+	}
+
+	if g.manifest != nil {
+		report.Checksum = g.manifest.Checksum
+	}
+
+	if image := g.BuildImage(); image != "" {
+		report.GoVersion = image
+	} else if version, err := g.goTool().env("", g.cmdEnv(), "GOVERSION"); err == nil {
+		report.GoVersion = version
+	}
+
+	return report, g.validateSize(report)
+}
+
+// validateSize checks the size of the binary against the size budget of
+// the function, the report of staged asset is checked against actual budget.
+func (g *GoCompiler) validateSize(report *BuildReport) error {
+	report.MaxSize = g.config.MaxBinarySize
+
+	if report.MaxSize > 0 && report.Size > report.MaxSize {
+		return &BinarySizeError{
+			Package: g.sourceCode,
+			Size:    report.Size,
+			MaxSize: report.MaxSize,
+		}
+	}
+
+	return nil
+}
+
+// zipSize estimates size of the asset zipped by AWS CDK
+func zipSize(dir string) (int64, error) {
+	w := &countWriter{}
+	z := zip.NewWriter(w)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := z.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		r, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		_, err = io.Copy(f, r)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := z.Close(); err != nil {
+		return 0, err
+	}
+
+	return w.n, nil
+}
+
+type countWriter struct{ n int64 }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
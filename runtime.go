@@ -10,12 +10,17 @@ package scud
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime/debug"
+	"strings"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awss3assets"
+	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
 )
 
@@ -26,36 +31,206 @@ type Compiler interface {
 	SourceCodeVersion() string
 }
 
+// CompilerConfig is optionally implemented by Compiler, the configuration of
+// the build is an input of asset hash. Compiler that does not implement it
+// is hashed as the function built with default toolchain (NewGoCompiler).
+type CompilerConfig interface {
+	BuildEnv() []string
+	BuildFlags() []string
+	LocalModules() []string
+	BuildImage() string
+	CompressFlags() []string
+}
+
 // AssetCodeGo bundles lambda function from source code
 func AssetCodeGo(compiler Compiler) awslambda.Code {
-	hash := NewHasher(false)
-	checksum, err := hash.Hash(
-		compiler.SourceCodeModule(),
-		compiler.SourceCodeLambda(),
-		compiler.SourceCodeVersion(),
-	)
+	code, _ := assetCodeGo(compiler)
+	return code
+}
+
+func assetCodeGo(compiler Compiler) (awslambda.Code, *Manifest) {
+	gocc, _ := compiler.(*GoCompiler)
+
+	var manifest *Manifest
+	if gocc != nil && gocc.manifest != nil {
+		manifest = gocc.manifest
+	} else {
+		hash := NewHasher(os.Getenv("SCUD_HASH_VERBOSE") == "1")
+		m, err := hash.Manifest(compiler)
+		if err != nil {
+			panic(fmt.Errorf("failed to compute hash of the source code: %w", err))
+		}
+		manifest = m
+	}
+
+	// the asset hash is the key of the build cache
+	if gocc != nil {
+		gocc.manifest = manifest
+	}
+
+	image := ""
+	if config, ok := compiler.(CompilerConfig); ok {
+		image = config.BuildImage()
+	}
+	if image == "" {
+		image = "golang"
+	}
+
+	// the asset is resolved from working directory of the application,
+	// the path is absolute because jsii runtime might have other one.
+	cwd, err := os.Getwd()
 	if err != nil {
-		panic(fmt.Errorf("failed to compute hash of the source code: %w", err))
+		panic(err)
 	}
 
-	return awslambda.NewAssetCode(
-		jsii.String("."),
+	code := awslambda.NewAssetCode(
+		jsii.String(cwd),
 		&awss3assets.AssetOptions{
 			AssetHashType: awscdk.AssetHashType_CUSTOM,
-			AssetHash:     jsii.String(checksum),
+			AssetHash:     jsii.String(manifest.Checksum),
 			Bundling: &awscdk.BundlingOptions{
-				Image: awscdk.DockerImage_FromRegistry(jsii.String("golang")),
+				// Note: the image is required by AWS CDK, the compiler always
+				//       builds the function either locally or inside container.
+				Image: awscdk.DockerImage_FromRegistry(jsii.String(image)),
 				Local: compiler.(awscdk.ILocalBundling),
-				// Note: it make no sense to build Golang code inside container
 			},
 		})
+
+	return code, manifest
+}
+
+// artifactPath returns path to the build artifact of the construct.
+// Artifacts are persisted next to the cloud assembly, at
+// cdk.out/scud/{construct path}{suffix}
+func artifactPath(c constructs.Construct, suffix string) string {
+	stage := awscdk.Stage_Of(c)
+	if stage == nil || stage.Outdir() == nil {
+		return ""
+	}
+
+	name := strings.ReplaceAll(*c.Node().Path(), "/", ".")
+	return filepath.Join(*stage.Outdir(), "scud", name+suffix)
+}
+
+// childArtifactPath returns path to the build artifact of the construct
+// that is not created yet within the scope.
+func childArtifactPath(scope constructs.Construct, id string, suffix string) string {
+	stage := awscdk.Stage_Of(scope)
+	if stage == nil || stage.Outdir() == nil {
+		return ""
+	}
+
+	name := strings.ReplaceAll(path.Join(*scope.Node().Path(), id), "/", ".")
+	return filepath.Join(*stage.Outdir(), "scud", name+suffix)
+}
+
+// assetArtifactPath returns path to the build artifact of the asset staged
+// at the cloud assembly, cdk.out/scud/asset.{hash}{suffix}
+func assetArtifactPath(outdir, checksum, suffix string) string {
+	if outdir == "" {
+		return ""
+	}
+
+	return filepath.Join(outdir, "scud", "asset."+checksum+suffix)
 }
 
-func rootSourceCode(sourceCodeModule string) string {
-	sourceCode := os.Getenv("GITHUB_WORKSPACE")
-	if sourceCode == "" {
-		sourceCode = filepath.Join(os.Getenv("GOPATH"), "src", sourceCodeModule)
+// stagedArtifacts keeps build information, report and ELF metadata of the
+// asset next to the cloud assembly, at cdk.out/scud/asset.{hash}.*. AWS CDK
+// does not bundle the asset that is already staged, artifacts of the build
+// are restored then. The size budget and runtime are not inputs of the asset
+// hash, the restored artifacts are validated against the function.
+// Artifacts of the construct are removed if they are not available at all.
+func stagedArtifacts(c constructs.Construct, gocc *GoCompiler) {
+	stage := awscdk.Stage_Of(c)
+	if stage == nil || stage.Outdir() == nil || gocc.manifest == nil {
+		return
+	}
+
+	asset := assetArtifactPath(*stage.Outdir(), gocc.manifest.Checksum, "")
+
+	if gocc.report != nil {
+		if err := WriteBuildReport(asset+".build.json", gocc.report); err != nil {
+			panic(err)
+		}
+		if gocc.buildInfo != nil {
+			if err := os.WriteFile(asset+".buildinfo", []byte(gocc.buildInfo.String()), 0664); err != nil {
+				panic(err)
+			}
+		}
+		if gocc.binary != nil {
+			if err := writeELF(asset+".elf.json", gocc.binary); err != nil {
+				panic(err)
+			}
+		}
+		return
+	}
+
+	if report, err := ReadBuildReport(asset + ".build.json"); err == nil {
+		if err := gocc.validateSize(report); err != nil {
+			panic(err)
+		}
+		gocc.report = report
+	}
+
+	if _, err := os.Stat(asset + ".elf.json"); err == nil {
+		bin, err := readELFOf(asset + ".elf.json")
+		if err != nil {
+			panic(err)
+		}
+		if err := gocc.validateELF(bin); err != nil {
+			panic(err)
+		}
+		gocc.binary = bin
+	}
+
+	if b, err := os.ReadFile(asset + ".buildinfo"); err == nil {
+		info, err := debug.ParseBuildInfo(string(b))
+		if err != nil {
+			panic(err)
+		}
+		gocc.buildInfo = info
+	}
+
+	if gocc.report == nil {
+		log.Printf("==> build report of %s is not available, the asset is staged\n", gocc.sourceCode)
+		for _, suffix := range []string{".build.json", gocc.config.SBOM.suffix()} {
+			if file := artifactPath(c, suffix); file != "" {
+				os.Remove(file)
+			}
+		}
+	}
+}
+
+// vulncheck scans the function for known vulnerabilities before build,
+// the report is written even if the synthesis fails.
+func vulncheck(scope constructs.Construct, id string, gocc *GoCompiler) {
+	report, err := gocc.Vulncheck()
+
+	if file := childArtifactPath(scope, id, ".vuln.json"); file != "" && report != nil {
+		if err := WriteVulnReport(file, report); err != nil {
+			panic(err)
+		}
+	}
+
+	if err != nil {
+		panic(err)
+	}
+}
+
+// lambdaRuntimes lists OS-only runtimes of Lambda with the version of glibc
+// provided by the runtime.
+// See https://docs.aws.amazon.com/lambda/latest/dg/lambda-runtimes.html
+var lambdaRuntimes = map[string]string{
+	"provided.al2023": "2.34",
+	"provided.al2":    "2.26",
+}
+
+const defaultRuntime = "provided.al2023"
+
+func validateRuntime(runtime string) error {
+	if _, has := lambdaRuntimes[runtime]; !has {
+		return fmt.Errorf("runtime %s is not supported, Go function requires OS-only runtime provided.al2023 or provided.al2", runtime)
 	}
 
-	return sourceCode
+	return nil
 }
@@ -0,0 +1,416 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+)
+
+// SBOMFormat defines format of software bill of materials
+type SBOMFormat string
+
+const (
+	// CycloneDX 1.5 JSON (default)
+	SBOMCycloneDX SBOMFormat = "cyclonedx"
+
+	// SPDX 2.3 JSON
+	SBOMSPDX SBOMFormat = "spdx"
+)
+
+// suffix of the SBOM file
+func (f SBOMFormat) suffix() string {
+	if f == SBOMSPDX {
+		return ".spdx.json"
+	}
+	return ".cdx.json"
+}
+
+func validateSBOMFormat(f SBOMFormat) error {
+	switch f {
+	case "", SBOMCycloneDX, SBOMSPDX:
+		return nil
+	default:
+		return fmt.Errorf("SBOM format %s is not supported", f)
+	}
+}
+
+// The build information of the binary (`go version -m`) is persisted next to
+// the binary because it is not readable from the compressed binary. The file
+// is removed from the asset by the compiler.
+const goBuildInfo = "bootstrap.buildinfo"
+
+// metadata key of the construct that refers to SBOM file
+const sbomMetadata = "scud:sbom"
+
+// writeBuildInfo persists build information of the binary
+func writeBuildInfo(outputDir string) error {
+	info, err := buildinfo.ReadFile(filepath.Join(outputDir, goBinary))
+	if err != nil {
+		return fmt.Errorf("unable to read build info of %s: %w", goBinary, err)
+	}
+
+	return os.WriteFile(filepath.Join(outputDir, goBuildInfo), []byte(info.String()), 0664)
+}
+
+// readBuildInfo reads build information of the binary and removes the file
+// from the output directory. The build information is read from the binary
+// if the file does not exist, it is not available if the binary is compressed.
+func readBuildInfo(outputDir string) (*debug.BuildInfo, error) {
+	file := filepath.Join(outputDir, goBuildInfo)
+
+	b, err := os.ReadFile(file)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		info, err := buildinfo.ReadFile(filepath.Join(outputDir, goBinary))
+		if err != nil {
+			log.Printf("==> build info of %s is not available: %s\n", goBinary, err)
+			return nil, nil
+		}
+		return info, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := os.Remove(file); err != nil {
+		return nil, err
+	}
+
+	return debug.ParseBuildInfo(string(b))
+}
+
+// SBOM returns software bill of materials of the function binary in
+// the format configured by the toolchain. It is nil if the function
+// is not built by this compiler (e.g. the asset is already staged).
+func (g *GoCompiler) SBOM() ([]byte, error) {
+	if g.buildInfo == nil {
+		return nil, nil
+	}
+
+	switch g.config.SBOM {
+	case SBOMSPDX:
+		return g.spdx()
+	default:
+		return g.cyclonedx()
+	}
+}
+
+// writeSBOM persists SBOM of the function next to the cloud assembly and
+// refers it from the construct metadata.
+func writeSBOM(c constructs.Construct, gocc *GoCompiler) {
+	sbom, err := gocc.SBOM()
+	if err != nil {
+		panic(err)
+	}
+
+	file := artifactPath(c, gocc.config.SBOM.suffix())
+	if sbom == nil || file == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(file), 0775); err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(file, sbom, 0664); err != nil {
+		panic(err)
+	}
+
+	c.Node().AddMetadata(jsii.String(sbomMetadata), jsii.String(file), nil)
+}
+
+// SBOMOf returns path to software bill of materials of scud function,
+// the file is available after the function is created. The empty string is
+// returned if the function does not have SBOM.
+func SBOMOf(c constructs.Construct) string {
+	for _, entry := range *c.Node().Metadata() {
+		if entry.Type != nil && *entry.Type == sbomMetadata {
+			if file, ok := entry.Data.(string); ok {
+				return file
+			}
+		}
+	}
+
+	return ""
+}
+
+// sbomComponent is the module linked into the binary
+type sbomComponent struct {
+	Path    string
+	Version string
+	Sum     string
+	Replace string
+}
+
+func (c sbomComponent) purl() string {
+	return "pkg:golang/" + c.Path + "@" + c.Version
+}
+
+// components returns modules of the binary, the standard library is
+// the last one.
+func (g *GoCompiler) components() (sbomComponent, []sbomComponent) {
+	info := g.buildInfo
+
+	main := sbomComponent{Path: info.Main.Path, Version: info.Main.Version}
+	if main.Path == "" {
+		main.Path = info.Path
+	}
+	if g.sourceCodeVersion != "" {
+		main.Version = g.sourceCodeVersion
+	}
+
+	deps := []sbomComponent{}
+	for _, dep := range info.Deps {
+		c := sbomComponent{Path: dep.Path, Version: dep.Version, Sum: dep.Sum}
+		if dep.Replace != nil {
+			c.Replace = dep.Replace.Path
+			if dep.Replace.Version != "" {
+				c.Replace += "@" + dep.Replace.Version
+				c.Version = dep.Replace.Version
+				c.Sum = dep.Replace.Sum
+			}
+		}
+		deps = append(deps, c)
+	}
+
+	deps = append(deps, sbomComponent{Path: "stdlib", Version: "v" + goSemver(info.GoVersion)})
+
+	return main, deps
+}
+
+// timestamp of the document, the SBOM is reproducible unless
+// SOURCE_DATE_EPOCH is defined.
+func sbomTimestamp() string {
+	t := time.Unix(0, 0)
+	if epoch, err := strconv.ParseInt(os.Getenv("SOURCE_DATE_EPOCH"), 10, 64); err == nil {
+		t = time.Unix(epoch, 0)
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// uuid derives stable identity of the document from its content
+func sbomUUID(seed string) string {
+	h := sha256.Sum256([]byte(seed))
+	h[6] = (h[6] & 0x0f) | 0x50
+	h[8] = (h[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
+
+//------------------------------------------------------------------------------
+//
+// CycloneDX (https://cyclonedx.org/docs/1.5/json/)
+//
+//------------------------------------------------------------------------------
+
+type cdxDocument struct {
+	BOMFormat    string          `json:"bomFormat"`
+	SpecVersion  string          `json:"specVersion"`
+	SerialNumber string          `json:"serialNumber"`
+	Version      int             `json:"version"`
+	Metadata     cdxMetadata     `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+func (g *GoCompiler) cyclonedx() ([]byte, error) {
+	main, deps := g.components()
+
+	props := []cdxProperty{{Name: "go:version", Value: g.buildInfo.GoVersion}}
+	for _, s := range g.buildInfo.Settings {
+		props = append(props, cdxProperty{Name: "go:build:" + s.Key, Value: s.Value})
+	}
+
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + sbomUUID(g.buildInfo.String()+g.sourceCodeVersion),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: sbomTimestamp(),
+			Tools: cdxTools{
+				Components: []cdxComponent{{Type: "application", Name: "scud"}},
+			},
+			Component: cdxComponent{
+				Type:       "application",
+				BOMRef:     main.purl(),
+				Name:       main.Path,
+				Version:    main.Version,
+				PURL:       main.purl(),
+				Properties: props,
+			},
+		},
+		Components: []cdxComponent{},
+	}
+
+	refs := []string{}
+	for _, dep := range deps {
+		var props []cdxProperty
+		if dep.Sum != "" {
+			props = append(props, cdxProperty{Name: "go:sum", Value: dep.Sum})
+		}
+		if dep.Replace != "" {
+			props = append(props, cdxProperty{Name: "go:replace", Value: dep.Replace})
+		}
+
+		doc.Components = append(doc.Components, cdxComponent{
+			Type:       "library",
+			BOMRef:     dep.purl(),
+			Name:       dep.Path,
+			Version:    dep.Version,
+			PURL:       dep.purl(),
+			Properties: props,
+		})
+		refs = append(refs, dep.purl())
+	}
+
+	doc.Dependencies = []cdxDependency{{Ref: main.purl(), DependsOn: refs}}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+//------------------------------------------------------------------------------
+//
+// SPDX (https://spdx.github.io/spdx-spec/v2.3/)
+//
+//------------------------------------------------------------------------------
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string            `json:"name"`
+	SPDXID           string            `json:"SPDXID"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func (g *GoCompiler) spdx() ([]byte, error) {
+	main, deps := g.components()
+
+	pkg := func(id string, c sbomComponent) spdxPackage {
+		return spdxPackage{
+			Name:             c.Path,
+			SPDXID:           id,
+			VersionInfo:      c.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.purl()},
+			},
+		}
+	}
+
+	settings := []string{"go:version=" + g.buildInfo.GoVersion}
+	for _, s := range g.buildInfo.Settings {
+		settings = append(settings, "go:build:"+s.Key+"="+s.Value)
+	}
+
+	root := pkg("SPDXRef-Package-0", main)
+	root.Comment = strings.Join(settings, "\n")
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              main.Path,
+		DocumentNamespace: "https://github.com/fogfish/scud/spdx/" + main.Path + "-" + sbomUUID(g.buildInfo.String()+g.sourceCodeVersion),
+		CreationInfo: spdxCreationInfo{
+			Created:  sbomTimestamp(),
+			Creators: []string{"Tool: scud"},
+		},
+		Packages: []spdxPackage{root},
+		Relationships: []spdxRelationship{
+			{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: root.SPDXID},
+		},
+	}
+
+	for i, dep := range deps {
+		p := pkg(fmt.Sprintf("SPDXRef-Package-%d", i+1), dep)
+		if dep.Replace != "" {
+			p.Comment = "go:replace=" + dep.Replace
+		}
+
+		doc.Packages = append(doc.Packages, p)
+		doc.Relationships = append(doc.Relationships,
+			spdxRelationship{SPDXElementID: root.SPDXID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: p.SPDXID},
+		)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -280,7 +280,7 @@ func (api *AuthorizerPublic) AddResource(
 ) *AuthorizerPublic {
 	lambda := integrations.NewHttpLambdaIntegration(
 		jsii.String(filepath.Base(endpoint)),
-		handler,
+		invokeTarget(handler),
 		&integrations.HttpLambdaIntegrationProps{
 			PayloadFormatVersion: apigw2.PayloadFormatVersion_VERSION_1_0(),
 		},
@@ -314,7 +314,7 @@ func (api *AuthorizerBasic) AddResource(
 ) *AuthorizerBasic {
 	lambda := integrations.NewHttpLambdaIntegration(
 		jsii.String(filepath.Base(endpoint)),
-		handler,
+		invokeTarget(handler),
 		&integrations.HttpLambdaIntegrationProps{
 			PayloadFormatVersion: apigw2.PayloadFormatVersion_VERSION_1_0(),
 		},
@@ -353,7 +353,7 @@ func (api *AuthorizerIAM) AddResource(
 ) *AuthorizerIAM {
 	lambda := integrations.NewHttpLambdaIntegration(
 		jsii.String(filepath.Base(endpoint)),
-		handler,
+		invokeTarget(handler),
 		&integrations.HttpLambdaIntegrationProps{
 			PayloadFormatVersion: apigw2.PayloadFormatVersion_VERSION_1_0(),
 		},
@@ -393,7 +393,7 @@ func (api *AuthorizerJwt) AddResource(
 ) *AuthorizerJwt {
 	lambda := integrations.NewHttpLambdaIntegration(
 		jsii.String(filepath.Base(endpoint)),
-		handler,
+		invokeTarget(handler),
 		&integrations.HttpLambdaIntegrationProps{
 			PayloadFormatVersion: apigw2.PayloadFormatVersion_VERSION_1_0(),
 		},
@@ -427,7 +427,7 @@ func (api *AuthorizerUniversal) AddResource(
 ) *AuthorizerUniversal {
 	lambda := integrations.NewHttpLambdaIntegration(
 		jsii.String(filepath.Base(endpoint)),
-		handler,
+		invokeTarget(handler),
 		&integrations.HttpLambdaIntegrationProps{
 			PayloadFormatVersion: apigw2.PayloadFormatVersion_VERSION_1_0(),
 		},
@@ -9,14 +9,31 @@
 package scud_test
 
 import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-cdk-go/awscdk/v2"
 	"github.com/aws/aws-cdk-go/awscdk/v2/assertions"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsapigatewayv2"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awsapplicationautoscaling"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awscodedeploy"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awsiam"
 	"github.com/aws/aws-cdk-go/awscdk/v2/awslambda"
+	"github.com/aws/aws-cdk-go/awscdk/v2/awslogs"
+	"github.com/aws/constructs-go/constructs/v10"
 	"github.com/aws/jsii-runtime-go"
+	"github.com/fogfish/it/v2"
 	"github.com/fogfish/scud"
 )
 
@@ -41,6 +58,121 @@ func TestFunctionGo(t *testing.T) {
 	for key, val := range require {
 		template.ResourceCountIs(key, val)
 	}
+
+	manifest, err := scud.ReadManifest(filepath.Join(*app.Outdir(), "scud", "Test.test.hash.json"))
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(manifest.Module, "github.com/fogfish/scud"),
+		it.Equal(manifest.Lambda, "test/lambda/go"),
+	)
+
+	report, err := scud.ReadBuildReport(filepath.Join(*app.Outdir(), "scud", "Test.test.build.json"))
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(report.Package, "github.com/fogfish/scud/test/lambda/go"),
+		it.Equal(report.Checksum, manifest.Checksum),
+		it.String(report.GoVersion).Contain("go"),
+		it.True(report.Size > 0),
+		it.True(report.ZipSize > 0 && report.ZipSize < report.Size),
+	)
+}
+
+// isolate runs the test in the child process, it returns true to the parent.
+// The jsii runtime does not complete the callback if it panics (e.g. the build
+// fails), the kernel nests all following requests into the pending callback
+// and runs out of the stack eventually. Tests of failed builds own the kernel.
+func isolate(t *testing.T) bool {
+	t.Helper()
+
+	if os.Getenv("SCUD_TEST_ISOLATED") == t.Name() {
+		return false
+	}
+
+	pattern := []string{}
+	for _, name := range strings.Split(t.Name(), "/") {
+		pattern = append(pattern, "^"+regexp.QuoteMeta(name)+"$")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run", strings.Join(pattern, "/"), "-test.count=1")
+	cmd.Env = append(os.Environ(), "SCUD_TEST_ISOLATED="+t.Name())
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %s\n%s", t.Name(), err, out)
+	}
+
+	return true
+}
+
+func TestFunctionGoSizeBudget(t *testing.T) {
+	if isolate(t) {
+		return
+	}
+
+	defer func() {
+		var err *scud.BinarySizeError
+		e, _ := recover().(error)
+		it.Then(t).Should(
+			it.True(errors.As(e, &err)),
+		)
+		it.Then(t).Should(
+			it.Equal(err.MaxSize, 1024),
+			it.True(err.Size > err.MaxSize),
+		)
+	}()
+
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+	scud.NewFunctionGo(stack, jsii.String("test"),
+		&scud.FunctionGoProps{
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/go",
+			Toolchain: &scud.Toolchain{
+				MaxBinarySize: 1024,
+			},
+		},
+	)
+}
+
+func TestFunctionGoSizeBudgetStaged(t *testing.T) {
+	if isolate(t) {
+		return
+	}
+
+	outdir := t.TempDir()
+
+	synth := func(size int64) (err error) {
+		defer func() {
+			if e := recover(); e != nil {
+				err, _ = e.(error)
+			}
+		}()
+
+		app := awscdk.NewApp(&awscdk.AppProps{Outdir: jsii.String(outdir)})
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+				Toolchain: &scud.Toolchain{
+					MaxBinarySize: size,
+				},
+			},
+		)
+
+		return nil
+	}
+
+	// the size budget is not an input of asset hash, the asset is staged
+	var err *scud.BinarySizeError
+	it.Then(t).Should(
+		it.Nil(synth(0)),
+		it.True(errors.As(synth(1024), &err)),
+	)
+	it.Then(t).Should(
+		it.Equal(err.MaxSize, 1024),
+		it.True(err.Size > err.MaxSize),
+	)
 }
 
 func TestFunctionGoArch(t *testing.T) {
@@ -87,6 +219,178 @@ func TestFunctionGoArch(t *testing.T) {
 	}
 }
 
+func TestFunctionGoBinaryMismatch(t *testing.T) {
+	for goenv, reason := range map[string]string{
+		"GOARCH=386":   "binary is built for EM_386 (GOARCH=386), Lambda architecture is arm64",
+		"GOOS=freebsd": "binary is built for ELFOSABI_FREEBSD (GOOS=freebsd), expected Linux",
+	} {
+		t.Run(goenv, func(t *testing.T) {
+			if isolate(t) {
+				return
+			}
+
+			defer func() {
+				var err *scud.BinaryError
+				e, _ := recover().(error)
+				it.Then(t).Should(
+					it.True(errors.As(e, &err)),
+				)
+				it.Then(t).Should(
+					it.Equal(err.Package, "github.com/fogfish/scud/test/lambda/go"),
+					it.String(err.Reason).Contain(reason),
+				)
+			}()
+
+			key, val, _ := strings.Cut(goenv, "=")
+
+			app := awscdk.NewApp(nil)
+			stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+			scud.NewFunctionGo(stack, jsii.String("test"),
+				&scud.FunctionGoProps{
+					SourceCodeModule: "github.com/fogfish/scud",
+					SourceCodeLambda: "test/lambda/go",
+					Toolchain: &scud.Toolchain{
+						GoEnv: map[string]string{key: val},
+					},
+				},
+			)
+		})
+	}
+}
+
+func TestFunctionGoRuntime(t *testing.T) {
+	for expect, rt := range map[string]awslambda.Runtime{
+		"provided.al2023": nil,
+		"provided.al2":    awslambda.Runtime_PROVIDED_AL2(),
+	} {
+		t.Run(expect, func(t *testing.T) {
+			app := awscdk.NewApp(nil)
+			stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+			scud.NewFunctionGo(stack, jsii.String("test"),
+				&scud.FunctionGoProps{
+					FunctionProps:    &awslambda.FunctionProps{Runtime: rt},
+					SourceCodeModule: "github.com/fogfish/scud",
+					SourceCodeLambda: "test/lambda/go",
+				},
+			)
+
+			template := assertions.Template_FromStack(stack, nil)
+			template.HasResourceProperties(jsii.String("AWS::Lambda::Function"),
+				map[string]any{
+					"Runtime": expect,
+					"Handler": "bootstrap",
+				},
+			)
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		defer func() {
+			e, _ := recover().(error)
+			it.Then(t).Should(
+				it.String(e.Error()).Contain("runtime nodejs20.x is not supported"),
+			)
+		}()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				FunctionProps:    &awslambda.FunctionProps{Runtime: awslambda.Runtime_NODEJS_20_X()},
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+			},
+		)
+	})
+
+	t.Run("GLIBC", func(t *testing.T) {
+		if isolate(t) {
+			return
+		}
+
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+
+		synth := func(rt awslambda.Runtime) (err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					err, _ = e.(error)
+				}
+			}()
+
+			app := awscdk.NewApp(nil)
+			stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+			scud.NewFunctionGo(stack, jsii.String("test"),
+				&scud.FunctionGoProps{
+					FunctionProps:    &awslambda.FunctionProps{Runtime: rt},
+					SourceCodeModule: "example.com/hasher",
+					SourceCodeLambda: "cmd/cgo",
+					Toolchain: &scud.Toolchain{
+						GoEnv: map[string]string{"GOARCH": runtime.GOARCH, "CGO_ENABLED": "1"},
+					},
+				},
+			)
+
+			return nil
+		}
+
+		var err *scud.BinaryError
+		it.Then(t).Should(
+			it.Nil(synth(awslambda.Runtime_PROVIDED_AL2023())),
+			it.True(errors.As(synth(awslambda.Runtime_PROVIDED_AL2()), &err)),
+		)
+		it.Then(t).Should(
+			it.String(err.Reason).Contain("runtime provided.al2 provides GLIBC_2.26"),
+		)
+	})
+
+	t.Run("Staged", func(t *testing.T) {
+		if isolate(t) {
+			return
+		}
+
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+		outdir := t.TempDir()
+
+		synth := func(rt awslambda.Runtime) (err error) {
+			defer func() {
+				if e := recover(); e != nil {
+					err, _ = e.(error)
+				}
+			}()
+
+			app := awscdk.NewApp(&awscdk.AppProps{Outdir: jsii.String(outdir)})
+			stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+			scud.NewFunctionGo(stack, jsii.String("test"),
+				&scud.FunctionGoProps{
+					FunctionProps:    &awslambda.FunctionProps{Runtime: rt},
+					SourceCodeModule: "example.com/hasher",
+					SourceCodeLambda: "cmd/cgo",
+					Toolchain: &scud.Toolchain{
+						GoEnv: map[string]string{"GOARCH": runtime.GOARCH, "CGO_ENABLED": "1"},
+					},
+				},
+			)
+
+			return nil
+		}
+
+		// the runtime is not an input of asset hash, the asset is staged
+		var err *scud.BinaryError
+		it.Then(t).Should(
+			it.Nil(synth(awslambda.Runtime_PROVIDED_AL2023())),
+			it.True(errors.As(synth(awslambda.Runtime_PROVIDED_AL2()), &err)),
+		)
+		it.Then(t).Should(
+			it.String(err.Reason).Contain("runtime provided.al2 provides GLIBC_2.26"),
+		)
+	})
+}
+
 func TestFunctionGoWithProps(t *testing.T) {
 	app := awscdk.NewApp(nil)
 	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
@@ -143,33 +447,1669 @@ func TestFunctionGoMany(t *testing.T) {
 	)
 }
 
-func TestFunctionGoContainer(t *testing.T) {
+func TestFunctionGoCompileError(t *testing.T) {
+	if isolate(t) {
+		return
+	}
+
+	root := fixture(t, "broken", t.TempDir())
+	t.Chdir(root)
+
+	defer func() {
+		var err *scud.CompileError
+		e, _ := recover().(error)
+		it.Then(t).Should(
+			it.True(errors.As(e, &err)),
+		)
+		it.Then(t).Should(
+			it.Equal(err.Package, "example.com/broken/cmd/lambda"),
+			it.Equal(err.ExitCode, 1),
+			it.String(err.Output).Contain("cmd/lambda/main.go:4"),
+		)
+	}()
+
 	app := awscdk.NewApp(nil)
 	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
 
-	scud.NewContainerGo(stack, jsii.String("test"),
-		&scud.ContainerGoProps{
+	scud.NewFunctionGo(stack, jsii.String("test"),
+		&scud.FunctionGoProps{
+			SourceCodeModule: "example.com/broken",
+			SourceCodeLambda: "cmd/lambda",
+		},
+	)
+
+	assertions.Template_FromStack(stack, nil)
+}
+
+func TestBuildCoordinator(t *testing.T) {
+	log := fakeGo(t)
+	t.Setenv("SCUD_BUILD_CACHE", t.TempDir())
+
+	calls := func(prefix string) []string {
+		b, err := os.ReadFile(log)
+		it.Then(t).Must(it.Nil(err))
+
+		seq := []string{}
+		for _, cmd := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(cmd, prefix) {
+				seq = append(seq, cmd)
+			}
+		}
+		return seq
+	}
+
+	app := awscdk.NewApp(nil)
+	amd64 := &scud.Toolchain{GoEnv: map[string]string{"GOARCH": "amd64"}}
+
+	// a and b are same asset, d is same function within other configuration
+	specs := map[string]*scud.FunctionGoProps{
+		"a": {SourceCodeModule: "github.com/fogfish/scud", SourceCodeLambda: "test/lambda/go"},
+		"b": {SourceCodeModule: "github.com/fogfish/scud", SourceCodeLambda: "test/lambda/go"},
+		"c": {SourceCodeModule: "github.com/fogfish/scud", SourceCodeLambda: "test/lambda/another"},
+		"d": {SourceCodeModule: "github.com/fogfish/scud", SourceCodeLambda: "test/lambda/go", Toolchain: amd64},
+	}
+
+	builds := scud.NewBuildCoordinator(2)
+	defer builds.Close()
+
+	for _, spec := range specs {
+		builds.Add(app, spec)
+	}
+	err := builds.Build()
+	it.Then(t).Must(it.Nil(err))
+
+	it.Then(t).Should(
+		it.Equal(len(calls("list -json -e -deps")), 2),
+		it.Equal(len(calls("build")), 3),
+	)
+
+	// same function is deployed to multiple stacks
+	stacks := []awscdk.Stack{}
+	for id, spec := range specs {
+		stack := awscdk.NewStack(app, jsii.String("Test"+id), nil)
+		scud.NewFunctionGo(stack, jsii.String(id), spec)
+		stacks = append(stacks, stack)
+	}
+
+	for _, stack := range stacks {
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Lambda::Function"), jsii.Number(1))
+	}
+
+	// functions are not rebuilt by AWS CDK
+	it.Then(t).Should(
+		it.Equal(len(calls("build")), 3),
+	)
+}
+
+func TestBuildCoordinatorStaged(t *testing.T) {
+	log := fakeGo(t)
+	t.Setenv("SCUD_BUILD_CACHE", t.TempDir())
+	outdir := t.TempDir()
+
+	synth := func() {
+		app := awscdk.NewApp(&awscdk.AppProps{Outdir: jsii.String(outdir)})
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+		spec := &scud.FunctionGoProps{
 			SourceCodeModule: "github.com/fogfish/scud",
 			SourceCodeLambda: "test/lambda/go",
-			StaticAssets: []string{
-				"test/lambda/go/main.go",
+		}
+
+		builds := scud.NewBuildCoordinator(2)
+		defer builds.Close()
+
+		builds.Add(stack, spec)
+		err := builds.Build()
+		it.Then(t).Must(it.Nil(err))
+
+		scud.NewFunctionGo(stack, jsii.String("test"), spec)
+		assertions.Template_FromStack(stack, nil)
+	}
+
+	builds := func() int {
+		b, err := os.ReadFile(log)
+		it.Then(t).Must(it.Nil(err))
+
+		n := 0
+		for _, cmd := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(cmd, "build ") {
+				n++
+			}
+		}
+		return n
+	}
+
+	synth()
+	it.Then(t).Should(it.Equal(builds(), 1))
+
+	// the asset is staged, neither coordinator nor AWS CDK build it again
+	synth()
+	it.Then(t).Should(it.Equal(builds(), 1))
+
+	// artifacts of the staged asset are not available, AWS CDK does not
+	// bundle the asset, the coordinator builds it
+	assets, err := filepath.Glob(filepath.Join(outdir, "scud", "asset.*"))
+	it.Then(t).Must(it.Nil(err))
+	for _, file := range assets {
+		it.Then(t).Must(it.Nil(os.Remove(file)))
+	}
+
+	t.Setenv("SCUD_BUILD_CACHE", t.TempDir())
+	synth()
+	it.Then(t).Should(it.Equal(builds(), 2))
+}
+
+func TestBuildCoordinatorDefaults(t *testing.T) {
+	synth := func(t *testing.T, at func(app awscdk.App, stack awscdk.Stack) constructs.Construct) awscdk.Stack {
+		t.Helper()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+		scud.NewDefaults(stack, jsii.String("Defaults"),
+			&scud.DefaultsProps{
+				Toolchain: &scud.Toolchain{GoEnv: map[string]string{"GOARCH": "amd64"}},
 			},
-			Packages: []string{
-				"zip",
+		)
+
+		spec := &scud.FunctionGoProps{
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/go",
+		}
+
+		builds := scud.NewBuildCoordinator(2)
+		defer builds.Close()
+
+		builds.Add(at(app, stack), spec)
+		err := builds.Build()
+		it.Then(t).Must(it.Nil(err))
+
+		scud.NewFunctionGo(stack, jsii.String("test"), spec)
+		return stack
+	}
+
+	t.Run("Scope", func(t *testing.T) {
+		log := fakeGo(t)
+		stack := synth(t, func(_ awscdk.App, stack awscdk.Stack) constructs.Construct { return stack })
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"),
+			map[string]any{"Architectures": []string{"x86_64"}},
+		)
+
+		b, err := os.ReadFile(log)
+		it.Then(t).Must(it.Nil(err))
+
+		builds := 0
+		for _, cmd := range strings.Split(string(b), "\n") {
+			if strings.HasPrefix(cmd, "build") {
+				builds++
+			}
+		}
+		it.Then(t).Should(it.Equal(builds, 1))
+	})
+
+	t.Run("OtherScope", func(t *testing.T) {
+		defer func() {
+			it.Then(t).ShouldNot(it.Nil(recover()))
+		}()
+
+		synth(t, func(app awscdk.App, _ awscdk.Stack) constructs.Construct { return app })
+	})
+}
+
+func TestBuildCoordinatorCompileError(t *testing.T) {
+	root := fixture(t, "broken", t.TempDir())
+	t.Chdir(root)
+
+	builds := scud.NewBuildCoordinator(2)
+	defer builds.Close()
+
+	builds.Add(awscdk.NewApp(nil),
+		&scud.FunctionGoProps{SourceCodeModule: "example.com/broken", SourceCodeLambda: "cmd/lambda"},
+	)
+
+	var err *scud.CompileError
+	it.Then(t).Should(
+		it.True(errors.As(builds.Build(), &err)),
+	)
+	it.Then(t).Should(
+		it.Equal(err.Package, "example.com/broken/cmd/lambda"),
+		it.String(err.Output).Contain("cmd/lambda/main.go:4"),
+	)
+}
+
+func TestFunctionGoBuildCache(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("SCUD_BUILD_CACHE", cache)
+
+	synth := func() (string, string) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+			},
+		)
+		assertions.Template_FromStack(stack, nil)
+
+		manifest, err := scud.ReadManifest(filepath.Join(*app.Outdir(), "scud", "Test.test.hash.json"))
+		it.Then(t).Must(it.Nil(err))
+
+		assets, err := filepath.Glob(filepath.Join(*app.Outdir(), "asset.*"))
+		it.Then(t).Must(it.Nil(err), it.Equal(len(assets), 1))
+
+		return manifest.Checksum, assets[0]
+	}
+
+	t.Run("Put", func(t *testing.T) {
+		checksum, _ := synth()
+
+		_, err := os.Stat(filepath.Join(cache, checksum, "bootstrap"))
+		it.Then(t).Should(it.Nil(err))
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		checksum, _ := synth()
+		err := os.WriteFile(filepath.Join(cache, checksum, "cached"), []byte{}, 0664)
+		it.Then(t).Must(it.Nil(err))
+
+		_, asset := synth()
+		_, err = os.Stat(filepath.Join(asset, "cached"))
+		it.Then(t).Should(it.Nil(err))
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		t.Setenv("SCUD_BUILD_CACHE_SIZE", "1K")
+
+		stale := filepath.Join(cache, "stale")
+		checks := filepath.Join(cache, "checks")
+		it.Then(t).Must(
+			it.Nil(os.MkdirAll(stale, 0775)),
+			it.Nil(os.WriteFile(filepath.Join(stale, "bootstrap"), make([]byte, 2048), 0664)),
+			it.Nil(os.MkdirAll(checks, 0775)),
+			it.Nil(os.Chtimes(checks, time.Unix(0, 0), time.Unix(0, 0))),
+		)
+
+		checksum, _ := synth()
+		os.RemoveAll(filepath.Join(cache, checksum))
+		synth()
+
+		_, err := os.Stat(stale)
+		it.Then(t).Should(
+			it.True(errors.Is(err, os.ErrNotExist)),
+		)
+		_, err = os.Stat(filepath.Join(cache, checksum))
+		it.Then(t).Should(it.Nil(err))
+		_, err = os.Stat(checks)
+		it.Then(t).Should(it.Nil(err))
+	})
+}
+
+func TestBuildModeDocker(t *testing.T) {
+	docker := func(t *testing.T, module, dir string, config *scud.Toolchain) (*scud.GoCompiler, []string) {
+		t.Helper()
+
+		log := fakeDocker(t)
+		t.Chdir(dir)
+		t.Setenv("GOPATH", t.TempDir())
+		t.Setenv("GOMODCACHE", t.TempDir())
+
+		gocc := scud.NewGoCompiler(module, "cmd/lambda", "", config)
+		out := t.TempDir()
+		it.Then(t).Must(it.Nil(gocc.Bundle(out)))
+
+		_, err := os.Stat(filepath.Join(out, "bootstrap"))
+		it.Then(t).Must(it.Nil(err))
+
+		args, err := os.ReadFile(log)
+		it.Then(t).Must(it.Nil(err))
+
+		return gocc, strings.Split(strings.TrimSpace(string(args)), "\n")
+	}
+
+	// pair of docker argument and its value, e.g. -v host:container
+	pair := func(args []string, flag, value string) bool {
+		for i := 0; i < len(args)-1; i++ {
+			if args[i] == flag && args[i+1] == value {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("Module", func(t *testing.T) {
+		root := fixture(t, "hasher", t.TempDir())
+		gocc, args := docker(t, "example.com/hasher", root,
+			&scud.Toolchain{
+				BuildMode: scud.BuildModeDocker,
+				GoEnv:     map[string]string{"GOARCH": "amd64"},
+			},
+		)
+
+		image := slices.Index(args, "golang:1.24")
+		it.Then(t).Should(
+			it.Equal(gocc.BuildImage(), "golang:1.24"),
+			it.Seq(args[:2]).Equal("run", "--rm"),
+			it.True(pair(args, "-v", root+":"+root)),
+			it.True(pair(args, "-w", root)),
+			it.True(pair(args, "-v", os.Getenv("GOMODCACHE")+":/go/pkg/mod")),
+			it.True(pair(args, "-e", "GOARCH=amd64")),
+			it.True(pair(args, "-e", "GOMODCACHE=/go/pkg/mod")),
+			it.True(pair(args, "-e", "GOCACHE=/go/cache")),
+			it.True(pair(args, "-o", "/asset-output/bootstrap")),
+			it.True(image > 0),
+			it.Equal(args[image+1], "go"),
+			it.Equal(args[image+2], "build"),
+			it.Equal(args[len(args)-1], "./cmd/lambda"),
+		)
+
+		for i, arg := range args {
+			if arg == "-e" {
+				key, _, _ := strings.Cut(args[i+1], "=")
+				it.Then(t).ShouldNot(
+					it.Seq([]string{"PATH", "GOPATH", "GOROOT", "TMPDIR", "GOWORK"}).Contain(key),
+				)
+			}
+		}
+	})
+
+	t.Run("Workspace", func(t *testing.T) {
+		root := fixture(t, "workspace", t.TempDir())
+		app := filepath.Join(root, "app")
+		write(t, filepath.Join(root, "go.work"), "go 1.24\n\ntoolchain go1.24.3\n\nuse (\n\t./app\n\t./lib\n)\n")
+
+		gocc, args := docker(t, "example.com/app", app,
+			&scud.Toolchain{BuildMode: scud.BuildModeDocker},
+		)
+
+		it.Then(t).Should(
+			it.Equal(gocc.BuildImage(), "golang:1.24.3"),
+			it.True(pair(args, "-v", root+":"+root)),
+			it.True(pair(args, "-w", app)),
+		)
+	})
+
+	t.Run("Image", func(t *testing.T) {
+		root := fixture(t, "hasher", t.TempDir())
+		gocc, args := docker(t, "example.com/hasher", root,
+			&scud.Toolchain{BuildMode: scud.BuildModeDocker, DockerImage: "golang:1.24.3-alpine"},
+		)
+
+		it.Then(t).Should(
+			it.Equal(gocc.BuildImage(), "golang:1.24.3-alpine"),
+			it.Seq(args).Contain("golang:1.24.3-alpine"),
+		)
+	})
+
+	t.Run("NoToolchain", func(t *testing.T) {
+		log := fakeDocker(t)
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+		t.Setenv("PATH", filepath.Dir(log))
+
+		err := scud.NewGoCompiler("example.com/hasher", "cmd/lambda", "", nil).Bundle(t.TempDir())
+		it.Then(t).Must(it.Fail(func() error { return err }))
+		it.Then(t).Should(
+			it.String(err.Error()).Contain("go toolchain is not found"),
+		)
+
+		_, err = os.Stat(log)
+		it.Then(t).Should(
+			it.True(errors.Is(err, os.ErrNotExist)),
+		)
+	})
+}
+
+func TestFunctionGoCompression(t *testing.T) {
+	fakeUPX(t)
+
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+	f := scud.NewFunctionGo(stack, jsii.String("test"),
+		&scud.FunctionGoProps{
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/go",
+			Toolchain: &scud.Toolchain{
+				Compression: &scud.Compression{Method: scud.CompressionNone},
 			},
+			Compression: &scud.Compression{Method: scud.CompressionUPX, Level: 9},
 		},
 	)
+	assertions.Template_FromStack(stack, nil)
 
-	require := map[*string]*float64{
-		jsii.String("AWS::IAM::Role"):        jsii.Number(1),
-		jsii.String("AWS::Lambda::Function"): jsii.Number(1),
-		jsii.String("AWS::Logs::LogGroup"):   jsii.Number(1),
-	}
+	asset, err := filepath.Glob(filepath.Join(*app.Outdir(), "asset.*", "*"))
+	it.Then(t).Must(it.Nil(err), it.Equal(len(asset), 1))
 
-	template := assertions.Template_FromStack(stack, nil)
-	for key, val := range require {
-		template.ResourceCountIs(key, val)
+	bin, err := os.ReadFile(asset[0])
+	it.Then(t).Should(
+		it.Nil(err),
+		it.Equal(filepath.Base(asset[0]), "bootstrap"),
+		it.String(string(bin)).HaveSuffix("upx"),
+		it.String(scud.SBOMOf(f)).Contain(".cdx.json"),
+	)
+}
+
+func TestFunctionGoCompressionNoTool(t *testing.T) {
+	if isolate(t) {
+		return
 	}
+
+	gobin, err := exec.LookPath("go")
+	it.Then(t).Must(it.Nil(err))
+	t.Setenv("PATH", filepath.Dir(gobin))
+
+	defer func() {
+		e, _ := recover().(error)
+		it.Then(t).Should(
+			it.String(e.Error()).Contain("compression requires upx"),
+		)
+	}()
+
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+	scud.NewFunctionGo(stack, jsii.String("test"),
+		&scud.FunctionGoProps{
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/go",
+			Compression:      &scud.Compression{Method: scud.CompressionUPX},
+		},
+	)
+}
+
+func TestFunctionGoChecks(t *testing.T) {
+	synth := func(t *testing.T, config *scud.Toolchain) (err error) {
+		t.Helper()
+
+		defer func() {
+			if e := recover(); e != nil {
+				err, _ = e.(error)
+			}
+		}()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "example.com/hasher",
+				SourceCodeLambda: "cmd/lambda",
+				Toolchain:        config,
+			},
+		)
+
+		return nil
+	}
+
+	for check, file := range map[string]string{
+		"vet":  "internal/core/vet.go",
+		"test": "internal/core/fail_test.go",
+		"lint": "",
+	} {
+		t.Run(check, func(t *testing.T) {
+			if isolate(t) {
+				return
+			}
+
+			root := fixture(t, "hasher", t.TempDir())
+			t.Chdir(root)
+			t.Setenv("SCUD_BUILD_CACHE", t.TempDir())
+
+			config := &scud.Toolchain{
+				Vet:    true,
+				Test:   true,
+				Linter: []string{"sh", "-c", "test ! -f lint.txt || (cat lint.txt; exit 1)", "lint"},
+			}
+			it.Then(t).Should(it.Nil(synth(t, config)))
+
+			switch check {
+			case "vet":
+				update(t, filepath.Join(root, "internal/core/core.go"))
+				write(t, filepath.Join(root, file), "package core\n\nimport \"fmt\"\n\nfunc Vet() { fmt.Printf(\"%d\", \"vet\") }\n")
+			case "test":
+				write(t, filepath.Join(root, file), "package core\n\nimport \"testing\"\n\nfunc TestFail(t *testing.T) { t.Fatal(\"test failed\") }\n")
+			case "lint":
+				update(t, filepath.Join(root, "internal/core/core.go"))
+				write(t, filepath.Join(root, "lint.txt"), "lint failed")
+			}
+
+			var err *scud.CheckError
+			it.Then(t).Should(
+				it.True(errors.As(synth(t, config), &err)),
+			)
+			it.Then(t).Should(
+				it.Equal(err.Check, check),
+				it.Equal(err.Package, "example.com/hasher/cmd/lambda"),
+				it.String(err.Output).Contain(check),
+			)
+		})
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		root := fixture(t, "hasher", t.TempDir())
+		t.Chdir(root)
+		t.Setenv("SCUD_BUILD_CACHE", t.TempDir())
+
+		config := &scud.Toolchain{
+			Linter: []string{"sh", "-c", "test ! -f lint.txt || (cat lint.txt; exit 1)", "lint"},
+		}
+		it.Then(t).Should(it.Nil(synth(t, config)))
+
+		// linter fails but the function is not changed
+		write(t, filepath.Join(root, "lint.txt"), "lint failed")
+		it.Then(t).Should(it.Nil(synth(t, config)))
+	})
+}
+
+func TestFunctionGoVulncheck(t *testing.T) {
+	synth := func(t *testing.T, config *scud.Toolchain) (report *scud.VulnReport, err error) {
+		t.Helper()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		defer func() {
+			report, _ = scud.ReadVulnReport(filepath.Join(*app.Outdir(), "scud", "Test.test.vuln.json"))
+			if e := recover(); e != nil {
+				err, _ = e.(error)
+			}
+		}()
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+				Toolchain:        config,
+			},
+		)
+
+		return nil, nil
+	}
+
+	ids := func(seq []scud.VulnFinding) []string {
+		ids := []string{}
+		for _, f := range seq {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+
+	db, err := filepath.Abs("testdata/vulndb")
+	it.Then(t).Must(it.Nil(err))
+
+	t.Run("Fail", func(t *testing.T) {
+		report, err := synth(t, &scud.Toolchain{
+			Vulncheck: &scud.Vulncheck{DB: db, Severity: scud.SeverityHigh},
+		})
+
+		var verr *scud.VulnerabilityError
+		it.Then(t).Should(
+			it.True(errors.As(err, &verr)),
+		)
+		it.Then(t).Should(
+			it.Equal(verr.Package, "github.com/fogfish/scud/test/lambda/go"),
+			it.Seq(ids(verr.Findings)).Equal("GO-2099-0001"),
+			it.Equal(verr.Findings[0].Severity, scud.SeverityHigh),
+			it.Equal(verr.Findings[0].Fixed, "1.51.0"),
+			it.Seq(ids(report.Findings)).Equal("GO-2099-0001", "GO-2099-0004"),
+		)
+	})
+
+	t.Run("BelowThreshold", func(t *testing.T) {
+		report, err := synth(t, &scud.Toolchain{
+			Vulncheck: &scud.Vulncheck{DB: db, Severity: scud.SeverityCritical},
+		})
+
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Equal(report.Modified, "2099-01-01T00:00:00Z"),
+			it.Seq(ids(report.Findings)).Equal("GO-2099-0001", "GO-2099-0004"),
+			it.Equal(report.Findings[1].Module, "stdlib"),
+			it.Equal(report.Findings[1].Severity, scud.SeverityLow),
+		)
+	})
+
+	t.Run("Zip", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "vulndb.zip")
+		zipDir(t, db, file)
+		t.Setenv("SCUD_VULN_DB", file)
+
+		_, err := synth(t, &scud.Toolchain{Vulncheck: &scud.Vulncheck{}})
+
+		var verr *scud.VulnerabilityError
+		it.Then(t).Should(
+			it.True(errors.As(err, &verr)),
+		)
+		it.Then(t).Should(
+			it.Seq(ids(verr.Findings)).Equal("GO-2099-0001", "GO-2099-0004"),
+		)
+	})
+
+	t.Run("UnknownSeverity", func(t *testing.T) {
+		unknown := t.TempDir()
+		it.Then(t).Must(it.Nil(os.CopyFS(unknown, os.DirFS(db))))
+
+		modules, err := os.ReadFile(filepath.Join(unknown, "index", "modules.json"))
+		it.Then(t).Must(it.Nil(err))
+		write(t, filepath.Join(unknown, "index", "modules.json"),
+			strings.Replace(string(modules), `{"id":"GO-2099-0004"`, `{"id":"GO-2099-0006","modified":"2099-01-01T00:00:00Z"},{"id":"GO-2099-0004"`, 1),
+		)
+		write(t, filepath.Join(unknown, "ID", "GO-2099-0006.json"), `{
+  "id": "GO-2099-0006",
+  "modified": "2099-01-01T00:00:00Z",
+  "affected": [
+    {
+      "package": {"name": "stdlib", "ecosystem": "Go"},
+      "ranges": [{"type": "SEMVER", "events": [{"introduced": "0"}]}]
+    }
+  ]
+}`)
+
+		_, err = synth(t, &scud.Toolchain{
+			Vulncheck: &scud.Vulncheck{DB: unknown, Severity: scud.SeverityCritical},
+		})
+
+		var verr *scud.VulnerabilityError
+		it.Then(t).Should(
+			it.True(errors.As(err, &verr)),
+		)
+		it.Then(t).Should(
+			it.Seq(ids(verr.Findings)).Equal("GO-2099-0006"),
+			it.Equal(verr.Findings[0].Severity, scud.SeverityUnknown),
+		)
+
+		report, err := synth(t, &scud.Toolchain{
+			Vulncheck: &scud.Vulncheck{DB: unknown, Severity: scud.SeverityCritical, Unknown: scud.SeverityHigh},
+		})
+		it.Then(t).Should(
+			it.Nil(err),
+			it.Seq(ids(report.Findings)).Equal("GO-2099-0001", "GO-2099-0004", "GO-2099-0006"),
+		)
+	})
+
+	t.Run("NoDatabase", func(t *testing.T) {
+		_, err := synth(t, &scud.Toolchain{
+			Vulncheck: &scud.Vulncheck{DB: filepath.Join(t.TempDir(), "vulndb.zip")},
+		})
+
+		it.Then(t).Should(
+			it.String(err.Error()).Contain("vulnerability database is not found"),
+		)
+	})
+}
+
+func zipDir(t *testing.T, dir, file string) {
+	t.Helper()
+
+	f, err := os.Create(file)
+	it.Then(t).Must(it.Nil(err))
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	defer w.Close()
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fw, err := w.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+
+		_, err = fw.Write(b)
+		return err
+	})
+	it.Then(t).Must(it.Nil(err))
+}
+
+func TestFunctionGoSBOM(t *testing.T) {
+	read := func(t *testing.T, file string) map[string]any {
+		t.Helper()
+
+		b, err := os.ReadFile(file)
+		it.Then(t).Must(it.Nil(err))
+
+		var doc map[string]any
+		it.Then(t).Must(it.Nil(json.Unmarshal(b, &doc)))
+
+		return doc
+	}
+
+	names := func(seq any, key string) []string {
+		names := []string{}
+		for _, c := range seq.([]any) {
+			names = append(names, c.(map[string]any)[key].(string))
+		}
+		return names
+	}
+
+	t.Run("CycloneDX", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		f := scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule:  "github.com/fogfish/scud",
+				SourceCodeLambda:  "test/lambda/go",
+				SourceCodeVersion: "v1.2.3",
+			},
+		)
+
+		file := scud.SBOMOf(f)
+		it.Then(t).Must(
+			it.Equal(file, filepath.Join(*app.Outdir(), "scud", "Test.test.cdx.json")),
+		)
+
+		doc := read(t, file)
+		main := doc["metadata"].(map[string]any)["component"].(map[string]any)
+		it.Then(t).Should(
+			it.Equal(doc["bomFormat"].(string), "CycloneDX"),
+			it.Equal(main["name"].(string), "github.com/fogfish/scud"),
+			it.Equal(main["purl"].(string), "pkg:golang/github.com/fogfish/scud@v1.2.3"),
+			it.Seq(names(doc["components"], "purl")).Contain("pkg:golang/github.com/aws/aws-lambda-go@v1.50.0"),
+			it.Seq(names(doc["components"], "name")).Contain("stdlib"),
+			it.Seq(names(main["properties"], "name")).Contain("go:build:GOARCH"),
+		)
+	})
+
+	t.Run("SPDX", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		f := scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+				Toolchain:        &scud.Toolchain{SBOM: scud.SBOMSPDX},
+			},
+		)
+
+		file := scud.SBOMOf(f)
+		it.Then(t).Must(
+			it.Equal(file, filepath.Join(*app.Outdir(), "scud", "Test.test.spdx.json")),
+		)
+
+		doc := read(t, file)
+		it.Then(t).Should(
+			it.Equal(doc["spdxVersion"].(string), "SPDX-2.3"),
+			it.Seq(names(doc["packages"], "name")).Contain("github.com/fogfish/scud"),
+			it.Seq(names(doc["packages"], "name")).Contain("github.com/aws/aws-lambda-go"),
+		)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		f := scud.NewContainerGo(stack, jsii.String("test"),
+			&scud.ContainerGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+				EmbedSBOM:        true,
+			},
+		)
+		assertions.Template_FromStack(stack, nil)
+
+		dockerfile, err := filepath.Glob(filepath.Join(*app.Outdir(), "asset.*", "Dockerfile"))
+		it.Then(t).Must(it.Nil(err), it.Equal(len(dockerfile), 1))
+
+		docker, err := os.ReadFile(dockerfile[0])
+		it.Then(t).Should(
+			it.Nil(err),
+			it.String(string(docker)).Contain("ADD sbom.cdx.json /opt/sbom.cdx.json"),
+			it.String(string(docker)).Contain("LABEL scud.sbom=/opt/sbom.cdx.json"),
+		)
+
+		embedded := read(t, filepath.Join(filepath.Dir(dockerfile[0]), "sbom.cdx.json"))
+		exposed := read(t, scud.SBOMOf(f))
+		it.Then(t).Should(
+			it.Equiv(embedded, exposed),
+		)
+	})
+
+	t.Run("Staged", func(t *testing.T) {
+		log := fakeGo(t)
+		outdir := t.TempDir()
+
+		synth := func() (string, string) {
+			app := awscdk.NewApp(&awscdk.AppProps{Outdir: jsii.String(outdir)})
+			stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+			f := scud.NewFunctionGo(stack, jsii.String("test"),
+				&scud.FunctionGoProps{
+					SourceCodeModule: "github.com/fogfish/scud",
+					SourceCodeLambda: "test/lambda/go",
+				},
+			)
+
+			return scud.SBOMOf(f), filepath.Join(outdir, "scud", "Test.test.build.json")
+		}
+
+		builds := func() int {
+			b, err := os.ReadFile(log)
+			it.Then(t).Must(it.Nil(err))
+
+			n := 0
+			for _, cmd := range strings.Split(string(b), "\n") {
+				if strings.HasPrefix(cmd, "build ") {
+					n++
+				}
+			}
+			return n
+		}
+
+		sbom, report := synth()
+		built := read(t, sbom)
+		it.Then(t).Must(
+			it.Nil(os.Remove(sbom)),
+			it.Nil(os.Remove(report)),
+		)
+
+		// the asset is staged, AWS CDK does not bundle it again
+		sbom, report = synth()
+		_, err := os.Stat(report)
+		it.Then(t).Should(
+			it.Equal(builds(), 1),
+			it.Nil(err),
+			it.Equiv(read(t, sbom), built),
+		)
+
+		// artifacts of the asset are not available, stale files are removed
+		assets, err := filepath.Glob(filepath.Join(outdir, "scud", "asset.*"))
+		it.Then(t).Must(it.Nil(err), it.Equal(len(assets), 3))
+		for _, file := range assets {
+			it.Then(t).Must(it.Nil(os.Remove(file)))
+		}
+
+		sbom, report = synth()
+		_, err = os.Stat(report)
+		it.Then(t).Should(
+			it.Equal(sbom, ""),
+			it.True(errors.Is(err, os.ErrNotExist)),
+		)
+		_, err = os.Stat(filepath.Join(outdir, "scud", "Test.test.cdx.json"))
+		it.Then(t).Should(
+			it.True(errors.Is(err, os.ErrNotExist)),
+		)
+	})
+}
+
+func TestFunctionGoCgo(t *testing.T) {
+	synth := func(t *testing.T, config *scud.Toolchain) (err error) {
+		t.Helper()
+
+		defer func() {
+			if e := recover(); e != nil {
+				err, _ = e.(error)
+			}
+		}()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "example.com/hasher",
+				SourceCodeLambda: "cmd/cgo",
+				Toolchain:        config,
+			},
+		)
+
+		return nil
+	}
+
+	t.Run("Toolchain", func(t *testing.T) {
+		for goarch, cc := range map[string]string{
+			"arm64": "CC=zig cc -target aarch64-linux-musl",
+			"amd64": "CC=zig cc -target x86_64-linux-musl",
+		} {
+			gocc := scud.NewGoCompiler("example.com/hasher", "cmd/cgo", "",
+				&scud.Toolchain{
+					GoEnv: map[string]string{"GOARCH": goarch},
+					Cgo:   &scud.Cgo{},
+				},
+			)
+
+			it.Then(t).Should(
+				it.Seq(gocc.BuildEnv()).Contain(cc),
+				it.Seq(gocc.BuildEnv()).Contain("CGO_ENABLED=1"),
+				it.Seq(gocc.BuildFlags()).Contain("lambda.norpc,netgo,osusergo"),
+				it.Seq(gocc.BuildFlags()).Contain("-s -w -linkmode=external -extldflags=-static"),
+			)
+		}
+
+		gocc := scud.NewGoCompiler("example.com/hasher", "cmd/cgo", "",
+			&scud.Toolchain{
+				GoEnv: map[string]string{"CC": "clang"},
+				Cgo:   &scud.Cgo{Compiler: scud.CCompilerMusl},
+			},
+		)
+		it.Then(t).Should(
+			it.Seq(gocc.BuildEnv()).Contain("CC=clang"),
+			it.Seq(gocc.BuildEnv()).Contain("CXX=aarch64-linux-musl-g++"),
+		)
+	})
+
+	t.Run("Static", func(t *testing.T) {
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+		fakeZig(t, true)
+
+		it.Then(t).Should(
+			it.Nil(synth(t, &scud.Toolchain{
+				GoEnv: map[string]string{"GOARCH": runtime.GOARCH},
+				Cgo:   &scud.Cgo{},
+			})),
+		)
+	})
+
+	t.Run("Dynamic", func(t *testing.T) {
+		if isolate(t) {
+			return
+		}
+
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+		fakeZig(t, false)
+
+		var err *scud.BinaryError
+		it.Then(t).Should(
+			it.True(errors.As(synth(t, &scud.Toolchain{
+				GoEnv: map[string]string{"GOARCH": runtime.GOARCH},
+				Cgo:   &scud.Cgo{},
+			}), &err)),
+		)
+		it.Then(t).Should(
+			it.Equal(err.Package, "example.com/hasher/cmd/cgo"),
+			it.String(err.Reason).Contain("not statically linked"),
+		)
+	})
+
+	t.Run("Test", func(t *testing.T) {
+		if isolate(t) {
+			return
+		}
+
+		root := fixture(t, "hasher", t.TempDir())
+		t.Chdir(root)
+		t.Setenv("SCUD_BUILD_CACHE", t.TempDir())
+		write(t, filepath.Join(root, "cmd/cgo/main_test.go"), "package main\n\nimport \"testing\"\n\nfunc TestAnswer(t *testing.T) { main() }\n")
+
+		// zig cross-compiler is not usable on the host, tests use host C compiler
+		dir := t.TempDir()
+		script := "#!/bin/sh\n" +
+			"case \"$*\" in *--version) echo \"0.13.0\"; exit 0 ;; esac\n" +
+			"echo \"zig: target is not supported\" >&2\n" +
+			"exit 1\n"
+		it.Then(t).Must(it.Nil(os.WriteFile(filepath.Join(dir, "zig"), []byte(script), 0775)))
+		t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		var check *scud.CheckError
+		var compile *scud.CompileError
+		err := synth(t, &scud.Toolchain{
+			GoEnv: map[string]string{"GOARCH": runtime.GOARCH},
+			Cgo:   &scud.Cgo{},
+			Test:  true,
+		})
+		it.Then(t).ShouldNot(
+			it.True(errors.As(err, &check)),
+		).Should(
+			it.True(errors.As(err, &compile)),
+		)
+		it.Then(t).Should(
+			it.String(compile.Output).Contain("zig: target is not supported"),
+		)
+	})
+
+	t.Run("NoTool", func(t *testing.T) {
+		if isolate(t) {
+			return
+		}
+
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+
+		err := synth(t, &scud.Toolchain{Cgo: &scud.Cgo{Compiler: scud.CCompilerMusl}})
+		it.Then(t).Should(
+			it.String(err.Error()).Contain("cgo requires C compiler aarch64-linux-musl-gcc"),
+		)
+	})
+}
+
+func TestLogging(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+			},
+		)
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(1))
+		template.HasResource(jsii.String("AWS::Logs::LogGroup"),
+			map[string]any{
+				"Properties":     map[string]any{"RetentionInDays": 5},
+				"DeletionPolicy": "Delete",
+			},
+		)
+	})
+
+	t.Run("Shared", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewLogging(stack, jsii.String("Logging"),
+			&scud.LoggingProps{
+				Shared:              true,
+				LogGroupName:        jsii.String("/app/test"),
+				Retention:           awslogs.RetentionDays_ONE_WEEK,
+				RemovalPolicy:       awscdk.RemovalPolicy_RETAIN,
+				ApplicationLogLevel: awslambda.ApplicationLogLevel_DEBUG,
+			},
+		)
+
+		scope := constructs.NewConstruct(stack, jsii.String("Nested"))
+		scud.NewFunctionGo(scope, jsii.String("a"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+			},
+		)
+
+		scud.NewFunctionGo(stack, jsii.String("b"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/another",
+				Logging: &scud.LoggingProps{
+					SystemLogLevel: awslambda.SystemLogLevel_WARN,
+				},
+			},
+		)
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(1))
+		template.HasResource(jsii.String("AWS::Logs::LogGroup"),
+			map[string]any{
+				"Properties":     map[string]any{"LogGroupName": "/app/test", "RetentionInDays": 7},
+				"DeletionPolicy": "Retain",
+			},
+		)
+
+		for _, config := range []map[string]any{
+			{"LogFormat": "JSON", "ApplicationLogLevel": "DEBUG", "SystemLogLevel": assertions.Match_Absent()},
+			{"LogFormat": "JSON", "ApplicationLogLevel": "DEBUG", "SystemLogLevel": "WARN"},
+		} {
+			config["LogGroup"] = map[string]any{"Ref": assertions.Match_StringLikeRegexp(jsii.String("LoggingLogGroup"))}
+			template.HasResourceProperties(jsii.String("AWS::Lambda::Function"),
+				map[string]any{
+					"LoggingConfig": config,
+				},
+			)
+		}
+	})
+
+	t.Run("Containers", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewLogging(stack, jsii.String("Logging"),
+			&scud.LoggingProps{Retention: awslogs.RetentionDays_ONE_MONTH},
+		)
+
+		for id, lambda := range []string{"test/lambda/go", "test/lambda/another"} {
+			scud.NewContainerGo(stack, jsii.Sprintf("%d", id),
+				&scud.ContainerGoProps{
+					SourceCodeModule: "github.com/fogfish/scud",
+					SourceCodeLambda: lambda,
+				},
+			)
+		}
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(2))
+		template.AllResourcesProperties(jsii.String("AWS::Logs::LogGroup"),
+			map[string]any{"RetentionInDays": 30},
+		)
+
+		// the first container keeps the legacy id of log group
+		ids := []string{}
+		for id := range *template.FindResources(jsii.String("AWS::Logs::LogGroup"), nil) {
+			ids = append(ids, id)
+		}
+		slices.Sort(ids)
+		it.Then(t).Should(
+			it.String(ids[0]).HavePrefix("LogGroup"),
+			it.String(ids[1]).HavePrefix("Logsgthbfgfsscdtstlmbdanother"),
+		)
+	})
+
+	t.Run("InvalidFormat", func(t *testing.T) {
+		defer func() {
+			it.Then(t).ShouldNot(it.Nil(recover()))
+		}()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewLogging(stack, jsii.String("Logging"),
+			&scud.LoggingProps{
+				Format:         awslambda.LoggingFormat_TEXT,
+				SystemLogLevel: awslambda.SystemLogLevel_WARN,
+			},
+		)
+	})
+
+	t.Run("Apps", func(t *testing.T) {
+		// apps have constructs with identical paths
+		stacks := []awscdk.Stack{}
+		for range 2 {
+			app := awscdk.NewApp(nil)
+			stacks = append(stacks, awscdk.NewStack(app, jsii.String("Test"), nil))
+		}
+
+		scud.NewLogging(stacks[0], jsii.String("Logging"),
+			&scud.LoggingProps{Shared: true, Retention: awslogs.RetentionDays_ONE_WEEK},
+		)
+
+		for _, stack := range stacks {
+			scud.NewFunctionGo(stack, jsii.String("test"),
+				&scud.FunctionGoProps{
+					SourceCodeModule: "github.com/fogfish/scud",
+					SourceCodeLambda: "test/lambda/go",
+				},
+			)
+		}
+
+		for i, retention := range []int{7, 5} {
+			template := assertions.Template_FromStack(stacks[i], nil)
+			template.ResourceCountIs(jsii.String("AWS::Logs::LogGroup"), jsii.Number(1))
+			template.AllResourcesProperties(jsii.String("AWS::Logs::LogGroup"),
+				map[string]any{"RetentionInDays": retention},
+			)
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		defer func() {
+			it.Then(t).ShouldNot(it.Nil(recover()))
+		}()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewLogging(stack, jsii.String("A"), &scud.LoggingProps{})
+		scud.NewLogging(stack, jsii.String("B"), &scud.LoggingProps{})
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	app := awscdk.NewApp(nil)
+	scud.NewDefaults(app, jsii.String("Defaults"),
+		&scud.DefaultsProps{
+			Toolchain: &scud.Toolchain{
+				GoEnv: map[string]string{"GOARCH": "amd64"},
+			},
+			FunctionProps: &awslambda.FunctionProps{
+				MemorySize: jsii.Number(256),
+				Timeout:    awscdk.Duration_Seconds(jsii.Number(30)),
+				Environment: &map[string]*string{
+					"A": jsii.String("1"),
+					"B": jsii.String("1"),
+				},
+			},
+			Tags:    map[string]string{"team": "scud"},
+			Logging: &scud.LoggingProps{Retention: awslogs.RetentionDays_ONE_DAY},
+		},
+	)
+
+	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+	scud.NewDefaults(stack, jsii.String("Defaults"),
+		&scud.DefaultsProps{
+			FunctionProps: &awslambda.FunctionProps{
+				MemorySize: jsii.Number(512),
+				Environment: &map[string]*string{
+					"B": jsii.String("2"),
+				},
+			},
+		},
+	)
+
+	scud.NewFunctionGo(stack, jsii.String("test"),
+		&scud.FunctionGoProps{
+			FunctionProps: &awslambda.FunctionProps{
+				Environment: &map[string]*string{
+					"C": jsii.String("3"),
+				},
+			},
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/go",
+		},
+	)
+
+	scud.NewContainerGo(stack, jsii.String("container"),
+		&scud.ContainerGoProps{
+			DockerImageFunctionProps: &awslambda.DockerImageFunctionProps{
+				MemorySize: jsii.Number(1024),
+			},
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/another",
+		},
+	)
+
+	template := assertions.Template_FromStack(stack, nil)
+	template.ResourceCountIs(jsii.String("AWS::Lambda::Function"), jsii.Number(2))
+	template.AllResourcesProperties(jsii.String("AWS::Logs::LogGroup"),
+		map[string]any{"RetentionInDays": 1},
+	)
+
+	template.HasResourceProperties(jsii.String("AWS::Lambda::Function"),
+		map[string]any{
+			"Architectures": []string{"x86_64"},
+			"MemorySize":    512,
+			"Timeout":       30,
+			"Environment": map[string]any{
+				"Variables": map[string]any{"A": "1", "B": "2", "C": "3"},
+			},
+			"Tags": assertions.Match_ArrayWith(&[]any{
+				map[string]any{"Key": "team", "Value": "scud"},
+			}),
+		},
+	)
+
+	template.HasResourceProperties(jsii.String("AWS::Lambda::Function"),
+		map[string]any{
+			"Architectures": []string{"x86_64"},
+			"MemorySize":    1024,
+			"Timeout":       30,
+			"Environment": map[string]any{
+				"Variables": map[string]any{"A": "1", "B": "2"},
+			},
+			"Tags": assertions.Match_ArrayWith(&[]any{
+				map[string]any{"Key": "team", "Value": "scud"},
+			}),
+		},
+	)
+}
+
+func TestDefaultsApps(t *testing.T) {
+	// apps have constructs with identical paths
+	stacks := []awscdk.Stack{}
+	for range 2 {
+		app := awscdk.NewApp(nil)
+		stacks = append(stacks, awscdk.NewStack(app, jsii.String("Test"), nil))
+	}
+
+	scud.NewDefaults(stacks[0], jsii.String("Defaults"),
+		&scud.DefaultsProps{
+			FunctionProps: &awslambda.FunctionProps{MemorySize: jsii.Number(512)},
+		},
+	)
+
+	for _, stack := range stacks {
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+			},
+		)
+	}
+
+	for i, memory := range []any{512, assertions.Match_Absent()} {
+		template := assertions.Template_FromStack(stacks[i], nil)
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Function"),
+			map[string]any{"MemorySize": memory},
+		)
+	}
+}
+
+func TestDefaultsDuplicate(t *testing.T) {
+	defer func() {
+		it.Then(t).ShouldNot(it.Nil(recover()))
+	}()
+
+	app := awscdk.NewApp(nil)
+	scud.NewDefaults(app, jsii.String("A"), &scud.DefaultsProps{})
+	scud.NewDefaults(app, jsii.String("B"), &scud.DefaultsProps{})
+}
+
+func TestFunctionGoDeployment(t *testing.T) {
+	t.Run("Canary", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		f := scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+				Deployment: &scud.DeploymentProps{
+					Config:           awscodedeploy.LambdaDeploymentConfig_CANARY_10PERCENT_5MINUTES(),
+					RollbackOnErrors: true,
+				},
+			},
+		)
+
+		gw := scud.NewGateway(stack, jsii.String("GW"), &scud.GatewayProps{})
+		gw.NewAuthorizerPublic().AddResource("/test", f)
+
+		it.Then(t).ShouldNot(it.Nil(scud.AliasOf(f)))
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Lambda::Version"), jsii.Number(1))
+		template.ResourceCountIs(jsii.String("AWS::CloudWatch::Alarm"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Alias"),
+			map[string]any{"Name": "live"},
+		)
+		template.HasResourceProperties(jsii.String("AWS::CodeDeploy::DeploymentGroup"),
+			map[string]any{
+				"DeploymentConfigName": "CodeDeployDefault.LambdaCanary10Percent5Minutes",
+				"AlarmConfiguration":   map[string]any{"Enabled": true},
+				"AutoRollbackConfiguration": map[string]any{
+					"Enabled": true,
+					"Events": []string{
+						"DEPLOYMENT_FAILURE",
+						"DEPLOYMENT_STOP_ON_REQUEST",
+						"DEPLOYMENT_STOP_ON_ALARM",
+					},
+				},
+			},
+		)
+		template.HasResourceProperties(jsii.String("AWS::ApiGatewayV2::Integration"),
+			map[string]any{
+				"IntegrationUri": map[string]any{
+					"Ref": assertions.Match_StringLikeRegexp(jsii.String("testAlias")),
+				},
+			},
+		)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		f := scud.NewContainerGo(stack, jsii.String("test"),
+			&scud.ContainerGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+				Deployment:       &scud.DeploymentProps{Alias: "prod"},
+			},
+		)
+
+		it.Then(t).ShouldNot(it.Nil(scud.AliasOf(f)))
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::CloudWatch::Alarm"), jsii.Number(0))
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Alias"),
+			map[string]any{"Name": "prod"},
+		)
+		template.HasResourceProperties(jsii.String("AWS::CodeDeploy::DeploymentGroup"),
+			map[string]any{
+				"DeploymentConfigName": "CodeDeployDefault.LambdaAllAtOnce",
+				"AlarmConfiguration":   assertions.Match_Absent(),
+			},
+		)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		f := scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "github.com/fogfish/scud",
+				SourceCodeLambda: "test/lambda/go",
+			},
+		)
+
+		it.Then(t).Should(it.Nil(scud.AliasOf(f)))
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Lambda::Alias"), jsii.Number(0))
+		template.ResourceCountIs(jsii.String("AWS::CodeDeploy::DeploymentGroup"), jsii.Number(0))
+	})
+}
+
+func TestFunctionGoProvisionedConcurrency(t *testing.T) {
+	synth := func(config *scud.ProvisionedConcurrencyProps, deployment *scud.DeploymentProps) assertions.Template {
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule:       "github.com/fogfish/scud",
+				SourceCodeLambda:       "test/lambda/go",
+				Deployment:             deployment,
+				ProvisionedConcurrency: config,
+			},
+		)
+
+		return assertions.Template_FromStack(stack, nil)
+	}
+
+	t.Run("Fixed", func(t *testing.T) {
+		template := synth(&scud.ProvisionedConcurrencyProps{Executions: 5}, nil)
+
+		template.ResourceCountIs(jsii.String("AWS::ApplicationAutoScaling::ScalableTarget"), jsii.Number(0))
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Alias"),
+			map[string]any{
+				"Name": "live",
+				"ProvisionedConcurrencyConfig": map[string]any{
+					"ProvisionedConcurrentExecutions": 5,
+				},
+			},
+		)
+	})
+
+	t.Run("Utilization", func(t *testing.T) {
+		template := synth(
+			&scud.ProvisionedConcurrencyProps{Executions: 2, MaxCapacity: 20, Utilization: 0.7},
+			nil,
+		)
+
+		template.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalableTarget"),
+			map[string]any{
+				"MinCapacity":       2,
+				"MaxCapacity":       20,
+				"ScalableDimension": "lambda:function:ProvisionedConcurrency",
+				"ServiceNamespace":  "lambda",
+			},
+		)
+		template.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalingPolicy"),
+			map[string]any{
+				"TargetTrackingScalingPolicyConfiguration": map[string]any{
+					"TargetValue": 0.7,
+					"PredefinedMetricSpecification": map[string]any{
+						"PredefinedMetricType": "LambdaProvisionedConcurrencyUtilization",
+					},
+				},
+			},
+		)
+	})
+
+	t.Run("Schedule", func(t *testing.T) {
+		template := synth(
+			&scud.ProvisionedConcurrencyProps{
+				MaxCapacity: 50,
+				Schedules: []scud.ProvisionedConcurrencySchedule{
+					{
+						Name: "BusinessHours",
+						Schedule: awsapplicationautoscaling.Schedule_Cron(
+							&awsapplicationautoscaling.CronOptions{
+								Minute:  jsii.String("0"),
+								Hour:    jsii.String("8"),
+								WeekDay: jsii.String("MON-FRI"),
+							},
+						),
+						MinCapacity: 10,
+						MaxCapacity: 50,
+						TimeZone:    awscdk.TimeZone_EUROPE_HELSINKI(),
+					},
+					{
+						Name: "OffHours",
+						Schedule: awsapplicationautoscaling.Schedule_Cron(
+							&awsapplicationautoscaling.CronOptions{
+								Minute:  jsii.String("0"),
+								Hour:    jsii.String("18"),
+								WeekDay: jsii.String("MON-FRI"),
+							},
+						),
+						TimeZone: awscdk.TimeZone_EUROPE_HELSINKI(),
+					},
+				},
+			},
+			&scud.DeploymentProps{Alias: "prod"},
+		)
+
+		template.ResourceCountIs(jsii.String("AWS::Lambda::Alias"), jsii.Number(1))
+		template.HasResourceProperties(jsii.String("AWS::Lambda::Alias"),
+			map[string]any{"Name": "prod"},
+		)
+		template.HasResourceProperties(jsii.String("AWS::ApplicationAutoScaling::ScalableTarget"),
+			map[string]any{
+				"MinCapacity": 0,
+				"MaxCapacity": 50,
+				"ScheduledActions": []any{
+					map[string]any{
+						"ScheduledActionName":  "BusinessHours",
+						"Schedule":             "cron(0 8 ? * MON-FRI *)",
+						"ScalableTargetAction": map[string]any{"MinCapacity": 10, "MaxCapacity": 50},
+						"Timezone":             "Europe/Helsinki",
+					},
+					map[string]any{
+						"ScheduledActionName":  "OffHours",
+						"Schedule":             "cron(0 18 ? * MON-FRI *)",
+						"ScalableTargetAction": map[string]any{"MinCapacity": 0, "MaxCapacity": 0},
+						"Timezone":             "Europe/Helsinki",
+					},
+				},
+			},
+		)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for reason, config := range map[string]*scud.ProvisionedConcurrencyProps{
+			"requires executions":   {},
+			"requires max capacity": {Executions: 10, MaxCapacity: 5, Utilization: 0.5},
+			"is not in range":       {MaxCapacity: 5, Utilization: 70},
+			"has invalid capacity": {
+				MaxCapacity: 5,
+				Schedules: []scud.ProvisionedConcurrencySchedule{
+					{Name: "Peak", Schedule: awsapplicationautoscaling.Schedule_Rate(awscdk.Duration_Hours(jsii.Number(1))), MinCapacity: 5, MaxCapacity: 1},
+				},
+			},
+		} {
+			err := func() (err error) {
+				defer func() { err, _ = recover().(error) }()
+				synth(config, nil)
+				return nil
+			}()
+
+			it.Then(t).Should(
+				it.String(err.Error()).Contain(reason),
+			)
+		}
+	})
+}
+
+func TestFunctionGoContainer(t *testing.T) {
+	app := awscdk.NewApp(nil)
+	stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+	scud.NewContainerGo(stack, jsii.String("test"),
+		&scud.ContainerGoProps{
+			SourceCodeModule: "github.com/fogfish/scud",
+			SourceCodeLambda: "test/lambda/go",
+			StaticAssets: []string{
+				"test/lambda/go/main.go",
+			},
+			Packages: []string{
+				"zip",
+			},
+		},
+	)
+
+	require := map[*string]*float64{
+		jsii.String("AWS::IAM::Role"):        jsii.Number(1),
+		jsii.String("AWS::Lambda::Function"): jsii.Number(1),
+		jsii.String("AWS::Logs::LogGroup"):   jsii.Number(1),
+	}
+
+	template := assertions.Template_FromStack(stack, nil)
+	for key, val := range require {
+		template.ResourceCountIs(key, val)
+	}
+}
+
+func TestFunctionGoContainerRuntime(t *testing.T) {
+	// dynamically linked binary for musl libc (e.g. alpine image)
+	interp := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[runtime.GOARCH]
+	config := &scud.Toolchain{
+		GoEnv:   map[string]string{"GOARCH": runtime.GOARCH, "CGO_ENABLED": "1"},
+		LDFlags: []string{"-I", "/lib/ld-musl-" + interp + ".so.1"},
+	}
+
+	t.Run("Function", func(t *testing.T) {
+		if isolate(t) {
+			return
+		}
+
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+
+		defer func() {
+			var err *scud.BinaryError
+			e, _ := recover().(error)
+			it.Then(t).Should(
+				it.True(errors.As(e, &err)),
+			)
+			it.Then(t).Should(
+				it.String(err.Reason).Contain("it is not available in runtime provided.al2023"),
+			)
+		}()
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewFunctionGo(stack, jsii.String("test"),
+			&scud.FunctionGoProps{
+				SourceCodeModule: "example.com/hasher",
+				SourceCodeLambda: "cmd/cgo",
+				Toolchain:        config,
+			},
+		)
+	})
+
+	t.Run("Container", func(t *testing.T) {
+		t.Chdir(fixture(t, "hasher", t.TempDir()))
+
+		app := awscdk.NewApp(nil)
+		stack := awscdk.NewStack(app, jsii.String("Test"), nil)
+
+		scud.NewContainerGo(stack, jsii.String("test"),
+			&scud.ContainerGoProps{
+				SourceCodeModule: "example.com/hasher",
+				SourceCodeLambda: "cmd/cgo",
+				Packages:         []string{"musl"},
+				Toolchain:        config,
+			},
+		)
+
+		template := assertions.Template_FromStack(stack, nil)
+		template.ResourceCountIs(jsii.String("AWS::Lambda::Function"), jsii.Number(1))
+	})
 }
 
 func TestFunctionGoContainerArch(t *testing.T) {
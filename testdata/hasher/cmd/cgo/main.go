@@ -0,0 +1,12 @@
+package main
+
+/*
+int answer() { return 42; }
+*/
+import "C"
+
+import "fmt"
+
+func main() {
+	fmt.Println(int(C.answer()))
+}
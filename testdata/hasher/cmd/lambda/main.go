@@ -0,0 +1,13 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/hasher/internal/core"
+)
+
+var version = "0.0.0"
+
+func main() {
+	fmt.Println(core.Hello(), version)
+}
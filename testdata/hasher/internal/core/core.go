@@ -0,0 +1,8 @@
+package core
+
+import _ "embed"
+
+//go:embed hello.txt
+var hello string
+
+func Hello() string { return hello }
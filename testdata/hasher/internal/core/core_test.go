@@ -0,0 +1,9 @@
+package core
+
+import "testing"
+
+func TestHello(t *testing.T) {
+	if Hello() == "" {
+		t.Fatal("hello is empty")
+	}
+}
@@ -0,0 +1,5 @@
+//go:build dynamodb
+
+package core
+
+const Storage = "dynamodb"
@@ -0,0 +1,662 @@
+//
+// Copyright (C) 2020 - 2025 Dmitry Kolesnikov
+//
+// This file may be modified and distributed under the terms
+// of the MIT license.  See the LICENSE file for details.
+// https://github.com/fogfish/scud
+//
+
+package scud
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vulncheck configures scanning of the function dependencies for known
+// vulnerabilities. The scan uses offline database in the format of Go
+// vulnerability database (https://go.dev/security/vuln/database), it does
+// not require network access.
+type Vulncheck struct {
+	// Path to the database, either the directory or zip archive of Go
+	// vulnerability database (e.g. https://vuln.go.dev/vulndb.zip).
+	// The environment variable SCUD_VULN_DB is used by default.
+	DB string
+
+	// Vulnerabilities of the severity and above fail synthesis, any known
+	// vulnerability fails synthesis by default. The threshold applies to
+	// graded vulnerabilities only, see Unknown.
+	Severity Severity
+
+	// Severity of vulnerabilities that are not graded by the database
+	// (no severity or CVSS v3 vector), they are reported as SeverityUnknown.
+	// Go vulnerability database does not grade vulnerabilities at all, every
+	// its finding is not graded. Such findings are SeverityCritical by
+	// default, they fail synthesis at any threshold.
+	Unknown Severity
+}
+
+// Severity of the vulnerability
+type Severity string
+
+const (
+	SeverityLow      Severity = "LOW"
+	SeverityMedium   Severity = "MEDIUM"
+	SeverityHigh     Severity = "HIGH"
+	SeverityCritical Severity = "CRITICAL"
+	SeverityUnknown  Severity = "UNKNOWN"
+)
+
+func (s Severity) rank() int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// VulnReport lists known vulnerabilities of the function dependencies
+type VulnReport struct {
+	// Package of the function
+	Package string `json:"package"`
+
+	// Last modification of the database
+	Modified string `json:"modified,omitempty"`
+
+	// Vulnerabilities affecting the function
+	Findings []VulnFinding `json:"findings"`
+}
+
+// VulnFinding is the vulnerability of the module used by the function
+type VulnFinding struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	Module   string   `json:"module"`
+	Version  string   `json:"version"`
+	Fixed    string   `json:"fixed,omitempty"`
+	Severity Severity `json:"severity"`
+	Packages []string `json:"packages,omitempty"`
+}
+
+// VulnerabilityError is returned when the function uses modules with
+// known vulnerabilities of the configured severity and above.
+type VulnerabilityError struct {
+	Package  string
+	Findings []VulnFinding
+}
+
+func (e *VulnerabilityError) Error() string {
+	seq := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		seq[i] = fmt.Sprintf("  %s (%s) %s@%s fixed in %s: %s", f.ID, f.Severity, f.Module, f.Version, or(f.Fixed, "N/A"), f.Summary)
+	}
+
+	return fmt.Sprintf("%s uses modules with known vulnerabilities:\n%s", e.Package, strings.Join(seq, "\n"))
+}
+
+func or(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+// WriteVulnReport persists the report as JSON file
+func WriteVulnReport(file string, r *VulnReport) error {
+	if err := os.MkdirAll(path.Dir(file), 0775); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b, 0664)
+}
+
+// ReadVulnReport reads the report from JSON file
+func ReadVulnReport(file string) (*VulnReport, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var r VulnReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, fmt.Errorf("invalid vulnerability report %s: %w", file, err)
+	}
+
+	return &r, nil
+}
+
+// Vulncheck scans dependencies of the function (modules and standard library)
+// for known vulnerabilities. The report is nil if scanning is not enabled.
+// The error VulnerabilityError is returned together with the report if
+// vulnerabilities exceed the configured severity.
+func (g *GoCompiler) Vulncheck() (*VulnReport, error) {
+	if g.config.Vulncheck == nil {
+		return nil, nil
+	}
+
+	t := time.Now()
+
+	file := or(g.config.Vulncheck.DB, os.Getenv("SCUD_VULN_DB"))
+	if file == "" {
+		return nil, errors.New("vulnerability database is not defined, use Vulncheck.DB or SCUD_VULN_DB")
+	}
+
+	db, err := openVulnDB(file)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	root, err := g.moduleDir()
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs, err := g.goTool().packageDeps(root, g.cmdEnv(), g.BuildFlags(), path.Join(g.sourceCodePackage, g.sourceCodeLambda))
+	if err != nil {
+		return nil, err
+	}
+
+	// version of the toolchain that builds the function, either the toolchain
+	// of the module (go.mod) or the toolchain inside the container
+	goversion, err := g.goTool().env(root, g.cmdEnv(), "GOVERSION")
+	if err != nil {
+		return nil, err
+	}
+
+	// module path -> version and imported packages
+	type module struct {
+		version string
+		pkgs    []string
+	}
+	mods := map[string]*module{}
+	for _, pkg := range pkgs {
+		switch {
+		case pkg.Standard:
+			if _, has := mods["stdlib"]; !has {
+				mods["stdlib"] = &module{version: goSemver(goversion)}
+			}
+			mods["stdlib"].pkgs = append(mods["stdlib"].pkgs, pkg.ImportPath)
+		case pkg.Module != nil && !pkg.Module.Main:
+			mod, version := pkg.Module.Path, pkg.Module.Version
+			if pkg.Module.Replace != nil {
+				version = pkg.Module.Replace.Version
+			}
+			if version == "" {
+				continue
+			}
+			if _, has := mods[mod]; !has {
+				mods[mod] = &module{version: version}
+			}
+			mods[mod].pkgs = append(mods[mod].pkgs, pkg.ImportPath)
+		}
+	}
+
+	index, err := db.modules()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VulnReport{Package: g.sourceCode, Modified: db.modified(), Findings: []VulnFinding{}}
+	for mod, m := range mods {
+		for _, id := range index[mod] {
+			entry, err := db.entry(id)
+			if err != nil {
+				return nil, err
+			}
+
+			if finding := entry.affects(mod, m.version, m.pkgs); finding != nil {
+				report.Findings = append(report.Findings, *finding)
+			}
+		}
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		if report.Findings[i].ID != report.Findings[j].ID {
+			return report.Findings[i].ID < report.Findings[j].ID
+		}
+		return report.Findings[i].Module < report.Findings[j].Module
+	})
+
+	log.Printf("==> vulncheck %s: %d finding(s) (%v)\n", g.sourceCode, len(report.Findings), time.Since(t))
+
+	threshold := g.config.Vulncheck.Severity
+	if threshold == "" {
+		threshold = SeverityLow
+	}
+
+	unknown := g.config.Vulncheck.Unknown
+	if unknown == "" {
+		unknown = SeverityCritical
+	}
+
+	failed := []VulnFinding{}
+	for _, f := range report.Findings {
+		severity := f.Severity
+		if severity == SeverityUnknown {
+			severity = unknown
+		}
+		if severity.rank() >= threshold.rank() {
+			failed = append(failed, f)
+		}
+	}
+
+	if len(failed) > 0 {
+		return report, &VulnerabilityError{Package: g.sourceCode, Findings: failed}
+	}
+
+	return report, nil
+}
+
+func validateVulncheck(v *Vulncheck) error {
+	if v == nil {
+		return nil
+	}
+
+	for _, severity := range []Severity{v.Severity, v.Unknown} {
+		if severity != "" && severity.rank() == 0 {
+			return fmt.Errorf("severity %s is not supported", severity)
+		}
+	}
+
+	return nil
+}
+
+//------------------------------------------------------------------------------
+//
+// Offline vulnerability database
+//
+//------------------------------------------------------------------------------
+
+// vulnDB is the Go vulnerability database, either directory or zip archive
+type vulnDB struct {
+	fs     fs.FS
+	closer io.Closer
+}
+
+func openVulnDB(file string) (*vulnDB, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("vulnerability database is not found: %w", err)
+	}
+
+	if fi.IsDir() {
+		return &vulnDB{fs: os.DirFS(file)}, nil
+	}
+
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vulnerability database %s: %w", file, err)
+	}
+
+	return &vulnDB{fs: z, closer: z}, nil
+}
+
+func (db *vulnDB) Close() error {
+	if db.closer != nil {
+		return db.closer.Close()
+	}
+	return nil
+}
+
+func (db *vulnDB) read(file string, v any) error {
+	b, err := fs.ReadFile(db.fs, file)
+	if err != nil {
+		return fmt.Errorf("invalid vulnerability database: %w", err)
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("invalid vulnerability database %s: %w", file, err)
+	}
+
+	return nil
+}
+
+// modified returns the last modification of the database
+func (db *vulnDB) modified() string {
+	var meta struct {
+		Modified string `json:"modified"`
+	}
+
+	if err := db.read("index/db.json", &meta); err != nil {
+		return ""
+	}
+
+	return meta.Modified
+}
+
+// modules returns the index of vulnerabilities by module path
+func (db *vulnDB) modules() (map[string][]string, error) {
+	var seq []struct {
+		Path  string `json:"path"`
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	}
+
+	if err := db.read("index/modules.json", &seq); err != nil {
+		return nil, err
+	}
+
+	index := map[string][]string{}
+	for _, mod := range seq {
+		for _, vuln := range mod.Vulns {
+			index[mod.Path] = append(index[mod.Path], vuln.ID)
+		}
+	}
+
+	return index, nil
+}
+
+func (db *vulnDB) entry(id string) (*osvEntry, error) {
+	var entry osvEntry
+	if err := db.read("ID/"+id+".json", &entry); err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// osvEntry is the subset of OSV schema (https://ossf.github.io/osv-schema/)
+type osvEntry struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Affected []struct {
+		Package struct {
+			Name string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+		EcosystemSpecific struct {
+			Imports []struct {
+				Path string `json:"path"`
+			} `json:"imports"`
+		} `json:"ecosystem_specific"`
+	} `json:"affected"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// affects returns the finding if the version of module is affected by
+// the vulnerability and the function imports affected packages.
+func (e *osvEntry) affects(mod, version string, pkgs []string) *VulnFinding {
+	for _, affected := range e.Affected {
+		if affected.Package.Name != mod {
+			continue
+		}
+
+		vulnerable, fixed := false, ""
+		for _, r := range affected.Ranges {
+			if r.Type != "SEMVER" {
+				continue
+			}
+
+			inRange := false
+			for _, ev := range r.Events {
+				if ev.Introduced != "" && (ev.Introduced == "0" || semverCompare(version, ev.Introduced) >= 0) {
+					inRange = true
+				}
+				if ev.Fixed != "" && semverCompare(version, ev.Fixed) >= 0 {
+					inRange = false
+				}
+				if ev.Fixed != "" && semverCompare(version, ev.Fixed) < 0 && fixed == "" {
+					fixed = ev.Fixed
+				}
+			}
+			vulnerable = vulnerable || inRange
+		}
+
+		if !vulnerable {
+			continue
+		}
+
+		// the module is affected if affected packages are not defined
+		imported := []string{}
+		for _, imp := range affected.EcosystemSpecific.Imports {
+			for _, pkg := range pkgs {
+				if pkg == imp.Path {
+					imported = append(imported, pkg)
+				}
+			}
+		}
+
+		if len(affected.EcosystemSpecific.Imports) > 0 && len(imported) == 0 {
+			continue
+		}
+
+		return &VulnFinding{
+			ID:       e.ID,
+			Aliases:  e.Aliases,
+			Summary:  e.Summary,
+			Module:   mod,
+			Version:  version,
+			Fixed:    fixed,
+			Severity: e.severity(),
+			Packages: imported,
+		}
+	}
+
+	return nil
+}
+
+// severity of the vulnerability either defined by database or computed
+// from CVSS v3 vector, it is unknown otherwise.
+func (e *osvEntry) severity() Severity {
+	switch strings.ToUpper(e.DatabaseSpecific.Severity) {
+	case "LOW":
+		return SeverityLow
+	case "MEDIUM", "MODERATE":
+		return SeverityMedium
+	case "HIGH":
+		return SeverityHigh
+	case "CRITICAL":
+		return SeverityCritical
+	}
+
+	for _, s := range e.Severity {
+		if s.Type != "CVSS_V3" {
+			continue
+		}
+
+		score, err := cvss3(s.Score)
+		if err != nil {
+			continue
+		}
+
+		switch {
+		case score >= 9.0:
+			return SeverityCritical
+		case score >= 7.0:
+			return SeverityHigh
+		case score >= 4.0:
+			return SeverityMedium
+		default:
+			return SeverityLow
+		}
+	}
+
+	return SeverityUnknown
+}
+
+// cvss3 computes base score of CVSS v3 vector
+// (https://www.first.org/cvss/v3.1/specification-document)
+func cvss3(vector string) (float64, error) {
+	metrics := map[string]string{}
+	for _, kv := range strings.Split(vector, "/")[1:] {
+		k, v, _ := strings.Cut(kv, ":")
+		metrics[k] = v
+	}
+
+	weight := func(metric string, weights map[string]float64) (float64, error) {
+		w, has := weights[metrics[metric]]
+		if !has {
+			return 0, fmt.Errorf("invalid CVSS vector %s: metric %s", vector, metric)
+		}
+		return w, nil
+	}
+
+	changed := metrics["S"] == "C"
+	prWeights := map[string]float64{"N": 0.85, "L": 0.62, "H": 0.27}
+	if changed {
+		prWeights = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}
+	}
+	cia := map[string]float64{"H": 0.56, "L": 0.22, "N": 0}
+
+	var av, ac, pr, ui, c, i, a float64
+	var err error
+	for _, m := range []struct {
+		metric  string
+		weights map[string]float64
+		value   *float64
+	}{
+		{"AV", map[string]float64{"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2}, &av},
+		{"AC", map[string]float64{"L": 0.77, "H": 0.44}, &ac},
+		{"PR", prWeights, &pr},
+		{"UI", map[string]float64{"N": 0.85, "R": 0.62}, &ui},
+		{"C", cia, &c},
+		{"I", cia, &i},
+		{"A", cia, &a},
+	} {
+		if *m.value, err = weight(m.metric, m.weights); err != nil {
+			return 0, err
+		}
+	}
+
+	iss := 1 - (1-c)*(1-i)*(1-a)
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	exploitability := 8.22 * av * ac * pr * ui
+
+	if impact <= 0 {
+		return 0, nil
+	}
+
+	if changed {
+		return roundup(math.Min(1.08*(impact+exploitability), 10)), nil
+	}
+	return roundup(math.Min(impact+exploitability, 10)), nil
+}
+
+func roundup(x float64) float64 {
+	n := int(math.Round(x * 100000))
+	if n%10000 == 0 {
+		return float64(n) / 100000
+	}
+	return (math.Floor(float64(n)/10000) + 1) / 10
+}
+
+// goSemver converts Go version (go1.24.3, go1.25rc1) to semantic version
+func goSemver(version string) string {
+	v := strings.TrimPrefix(version, "go")
+	v, _, _ = strings.Cut(v, " ")
+
+	pre := ""
+	for _, tag := range []string{"rc", "beta"} {
+		if before, after, has := strings.Cut(v, tag); has {
+			v, pre = before, "-"+tag+"."+after
+		}
+	}
+
+	if strings.Count(v, ".") == 1 {
+		v += ".0"
+	}
+
+	return v + pre
+}
+
+// semverCompare compares semantic versions, prefix v is optional
+func semverCompare(a, b string) int {
+	parse := func(v string) ([]string, string) {
+		v = strings.TrimPrefix(v, "v")
+		v, _, _ = strings.Cut(v, "+")
+		v, pre, _ := strings.Cut(v, "-")
+		return strings.Split(v, "."), pre
+	}
+
+	compareIdent := func(x, y string) int {
+		nx, ex := strconv.Atoi(x)
+		ny, ey := strconv.Atoi(y)
+		switch {
+		case ex == nil && ey == nil:
+			return nx - ny
+		case ex == nil:
+			return -1
+		case ey == nil:
+			return 1
+		default:
+			return strings.Compare(x, y)
+		}
+	}
+
+	va, pa := parse(a)
+	vb, pb := parse(b)
+
+	for i := 0; i < 3; i++ {
+		x, y := "0", "0"
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if c := compareIdent(x, y); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case pa == pb:
+		return 0
+	case pa == "":
+		return 1
+	case pb == "":
+		return -1
+	}
+
+	sa, sb := strings.Split(pa, "."), strings.Split(pb, ".")
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		if c := compareIdent(sa[i], sb[i]); c != 0 {
+			return c
+		}
+	}
+
+	return len(sa) - len(sb)
+}